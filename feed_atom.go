@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	Base     string       `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Links    []AtomLink   `xml:"link"`
+	Authors  []AtomPerson `xml:"author"`
+	Entries  []AtomEntry  `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      AtomText       `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Authors    []AtomPerson   `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText is an Atom text construct. Plain text and escaped HTML arrive as
+// character data, while type="xhtml" content is inline markup wrapped in a div.
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type != "xhtml" {
+		return strings.TrimSpace(t.Text)
+	}
+	inner := strings.TrimSpace(t.InnerXML)
+	// Strip the mandatory wrapping <div xmlns="http://www.w3.org/1999/xhtml">
+	if strings.HasPrefix(inner, "<div") && strings.HasSuffix(inner, "</div>") {
+		if end := strings.Index(inner, ">"); end != -1 {
+			inner = inner[end+1 : len(inner)-len("</div>")]
+		}
+	}
+	return strings.TrimSpace(inner)
+}
+
+// alternateLink picks the link that points at the human readable page:
+// rel="alternate" (or no rel, which defaults to alternate), preferring HTML.
+func alternateLink(links []AtomLink) string {
+	var fallback string
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if fallback == "" {
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
+
+// parseAtom decodes an Atom 1.0 document. Titles and summaries may be text,
+// HTML or XHTML constructs, and the item link is the entry's alternate link.
+func parseAtom(body []byte) (*RSSFeed, error) {
+	var atom AtomFeed
+	if err := unmarshalXML(body, &atom); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal atom feed: %w", err)
+	}
+
+	feed := &RSSFeed{Base: atom.Base}
+	feed.Channel.Title = atom.Title.String()
+	feed.Channel.Link = alternateLink(atom.Links)
+	feed.Channel.Description = atom.Subtitle.String()
+
+	for _, entry := range atom.Entries {
+		item := RSSItem{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			GUID:        strings.TrimSpace(entry.ID),
+			Base:        entry.Base,
+			PubDate:     strings.TrimSpace(entry.Published),
+		}
+		// Summary is optional in Atom, fall back to the full content
+		if item.Description == "" {
+			item.Description = entry.Content.String()
+		}
+		if item.PubDate == "" {
+			item.PubDate = strings.TrimSpace(entry.Updated)
+		}
+		// Some feeds only carry the permalink in the entry id
+		if item.Link == "" && strings.HasPrefix(entry.ID, "http") {
+			item.Link = strings.TrimSpace(entry.ID)
+		}
+		// Entries without their own author inherit the feed's
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = atom.Authors
+		}
+		for _, author := range authors {
+			name := author.Name
+			if strings.TrimSpace(name) == "" {
+				name = author.Email
+			}
+			item.Creators = append(item.Creators, name)
+		}
+		for _, category := range entry.Categories {
+			name := category.Label
+			if strings.TrimSpace(name) == "" {
+				name = category.Term
+			}
+			item.Categories = append(item.Categories, name)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+			}
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
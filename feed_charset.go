@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// Matches both <meta charset="..."> and the http-equiv Content-Type form
+var htmlMetaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([A-Za-z0-9._:-]+)`)
+
+// detectCharset finds the character encoding of a feed. The Content-Type
+// charset parameter wins over the XML declaration, as RFC 7303 requires.
+func detectCharset(contentType string, body []byte) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+	if match := xmlEncodingPattern.FindSubmatch(bytes.TrimSpace(body[:min(len(body), 256)])); match != nil {
+		return string(match[1])
+	}
+	// Web pages (discovery, full text) declare it in a meta tag near the top,
+	// XML without a declaration is UTF-8
+	head := body[:min(len(body), 1024)]
+	if !bytes.HasPrefix(bytes.TrimSpace(head), []byte("<?xml")) {
+		if match := htmlMetaCharsetPattern.FindSubmatch(head); match != nil {
+			return string(match[1])
+		}
+	}
+	return "utf-8"
+}
+
+// decodeToUTF8 transcodes a feed body to UTF-8 so the parsers only ever see
+// one encoding.
+func decodeToUTF8(contentType string, body []byte) ([]byte, error) {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")) // UTF-8 byte order mark
+
+	label := strings.ToLower(strings.TrimSpace(detectCharset(contentType, body)))
+	if label == "utf-8" || label == "utf8" || label == "us-ascii" {
+		return body, nil
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode %s body: %w", label, err)
+	}
+	return decoded, nil
+}
+
+// unmarshalXML decodes a document that decodeToUTF8 already converted. The
+// XML declaration may still name the original encoding, so the decoder is
+// told to read the input as-is instead of rejecting it.
+func unmarshalXML(body []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder.Decode(v)
+}
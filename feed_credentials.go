@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/Ernestlph/Blog_Aggregator/internal/secretbox"
+)
+
+// feedCredentials is the authentication applied when fetching a private
+// feed. It is stored encrypted in feeds.credentials.
+type feedCredentials struct {
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Token    string            `json:"token,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+func (c *feedCredentials) isEmpty() bool {
+	return c == nil || (c.Username == "" && c.Password == "" && c.Token == "" && len(c.Headers) == 0)
+}
+
+// describe summarises the credentials without revealing any secret.
+func (c *feedCredentials) describe() string {
+	if c.isEmpty() {
+		return "none"
+	}
+	var parts []string
+	if c.Username != "" {
+		parts = append(parts, "basic auth as "+c.Username)
+	}
+	if c.Token != "" {
+		parts = append(parts, "bearer token")
+	}
+	for name := range c.Headers {
+		parts = append(parts, "header "+name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type credentialHeadersKey struct{}
+
+// apply adds the credentials to an outgoing request. The returned request
+// remembers which custom headers it carries, so redirects to another host
+// can drop them.
+func (c *feedCredentials) apply(req *http.Request) *http.Request {
+	if c.isEmpty() {
+		return req
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if len(c.Headers) == 0 {
+		return req
+	}
+	names := make([]string, 0, len(c.Headers))
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+		names = append(names, name)
+	}
+	return req.WithContext(context.WithValue(req.Context(), credentialHeadersKey{}, names))
+}
+
+// stripCredentialHeaders removes the custom credential headers from a
+// redirect that leaves the original host. net/http already drops
+// Authorization and Cookie there, but copies every other header along.
+func stripCredentialHeaders(req, original *http.Request) {
+	if strings.EqualFold(req.URL.Host, original.URL.Host) {
+		return
+	}
+	names, _ := req.Context().Value(credentialHeadersKey{}).([]string)
+	for _, name := range names {
+		req.Header.Del(name)
+	}
+}
+
+// sameHost reports whether two URLs point at the same host and port.
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// parseCredentialArgs pulls the authentication flags out of a command's
+// arguments and returns the remaining positional arguments.
+//
+//	--basic <user:password>  --bearer <token>  --header <"Name: value">
+func parseCredentialArgs(args []string) (*feedCredentials, []string, error) {
+	creds := &feedCredentials{}
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if flag != "--basic" && flag != "--bearer" && flag != "--header" {
+			rest = append(rest, flag)
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, nil, fmt.Errorf("%s needs a value", flag)
+		}
+		value := args[i+1]
+		i++
+
+		switch flag {
+		case "--basic":
+			username, password, ok := strings.Cut(value, ":")
+			if !ok || username == "" {
+				return nil, nil, errors.New("--basic expects <user:password>")
+			}
+			creds.Username = username
+			creds.Password = password
+		case "--bearer":
+			creds.Token = value
+		case "--header":
+			name, headerValue, ok := strings.Cut(value, ":")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, nil, errors.New(`--header expects "Name: value"`)
+			}
+			if creds.Headers == nil {
+				creds.Headers = make(map[string]string)
+			}
+			creds.Headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(headerValue)
+		}
+	}
+	return creds, rest, nil
+}
+
+func credentialsKey(s *state) ([]byte, error) {
+	if s.cfg.CredentialsKey == "" {
+		return nil, errors.New("credentials_key must be set in .gatorconfig.json to use feed credentials")
+	}
+	key, err := secretbox.ParseKey(s.cfg.CredentialsKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials_key: %w", err)
+	}
+	return key, nil
+}
+
+// encryptCredentials seals creds for the given feed. Empty credentials are
+// stored as NULL.
+func encryptCredentials(s *state, feed database.Feed, creds *feedCredentials) ([]byte, error) {
+	if creds.isEmpty() {
+		return nil, nil
+	}
+	key, err := credentialsKey(s)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode credentials: %w", err)
+	}
+	// Bind the ciphertext to the feed so it can't be copied onto another row
+	return secretbox.Seal(key, plaintext, feed.ID[:])
+}
+
+// decryptCredentials returns the stored credentials of a feed, or nil when it
+// has none.
+func decryptCredentials(s *state, feed database.Feed) (*feedCredentials, error) {
+	if len(feed.Credentials) == 0 {
+		return nil, nil
+	}
+	key, err := credentialsKey(s)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := secretbox.Open(key, feed.Credentials, feed.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt credentials for feed %s: %w", feed.Name, err)
+	}
+	var creds feedCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("couldn't decode credentials for feed %s: %w", feed.Name, err)
+	}
+	return &creds, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltoken"
+)
+
+// Link types announced by <link rel="alternate"> that we know how to parse.
+// Plain application/json is left out: WordPress uses it to advertise its
+// REST API, not a feed.
+var discoverableFeedTypes = map[string]string{
+	"application/rss+xml":   "RSS",
+	"application/atom+xml":  "Atom",
+	"application/rdf+xml":   "RDF",
+	"application/feed+json": "JSON Feed",
+}
+
+type discoveredFeed struct {
+	Title string
+	Type  string
+	URL   string
+}
+
+// isHTMLPage reports whether a response is a web page rather than a feed.
+func isHTMLPage(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "text/html") {
+		return true
+	}
+	start := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.HasPrefix(start, []byte("<html"))
+}
+
+// discoverFeedLinks returns the feeds advertised in an HTML page, with their
+// URLs resolved against the page (or its <base href>).
+func discoverFeedLinks(pageURL string, body []byte) ([]discoveredFeed, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse page url: %w", err)
+	}
+
+	var feeds []discoveredFeed
+	seen := map[string]bool{}
+	for _, tok := range htmltoken.Tokenize(string(body)) {
+		if tok.Type != htmltoken.StartTagToken && tok.Type != htmltoken.SelfClosingTagToken {
+			continue
+		}
+		if tok.Data == "base" {
+			if href, ok := tok.AttrVal("href"); ok {
+				if baseURL, err := base.Parse(strings.TrimSpace(href)); err == nil {
+					base = baseURL
+				}
+			}
+			continue
+		}
+		if tok.Data != "link" {
+			continue
+		}
+
+		rel, _ := tok.AttrVal("rel")
+		if !containsField(rel, "alternate") {
+			continue
+		}
+		linkType, _ := tok.AttrVal("type")
+		feedType, ok := discoverableFeedTypes[strings.ToLower(strings.TrimSpace(linkType))]
+		if !ok {
+			continue
+		}
+		href, ok := tok.AttrVal("href")
+		if !ok || strings.TrimSpace(href) == "" {
+			continue
+		}
+		feedURL, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || seen[feedURL.String()] {
+			continue
+		}
+		seen[feedURL.String()] = true
+
+		title, _ := tok.AttrVal("title")
+		feeds = append(feeds, discoveredFeed{Title: strings.TrimSpace(title), Type: feedType, URL: feedURL.String()})
+	}
+	return feeds, nil
+}
+
+// containsField reports whether a space separated attribute such as rel
+// contains value.
+func containsField(attr, value string) bool {
+	for _, field := range strings.Fields(attr) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFeedURL checks what rawURL points at. Feeds are returned unchanged,
+// while for HTML pages the advertised feed is returned instead, asking the
+// user to choose when the page lists several. Either way the URL returned
+// has been fetched and parsed as a feed.
+func resolveFeedURL(ctx context.Context, client *feedClient, rawURL string, creds *feedCredentials) (string, error) {
+	page, err := fetchForDiscovery(ctx, client, rawURL, creds)
+	if err != nil {
+		return "", err
+	}
+	if !isHTMLPage(page.contentType, page.body) {
+		if err := checkFeed(page.contentType, page.body); err != nil {
+			return "", fmt.Errorf("%s is not a feed: %w", rawURL, err)
+		}
+		return rawURL, nil
+	}
+
+	// Redirects may have moved us, relative links are relative to the final page
+	feeds, err := discoverFeedLinks(page.url, page.body)
+	if err != nil {
+		return "", err
+	}
+
+	var feedURL string
+	switch len(feeds) {
+	case 0:
+		return "", fmt.Errorf("%s is a web page and does not advertise any feeds", rawURL)
+	case 1:
+		fmt.Printf("Discovered %s feed: %s\n", feeds[0].Type, feeds[0].URL)
+		feedURL = feeds[0].URL
+	default:
+		feedURL, err = chooseFeed(feeds, os.Stdin)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The credentials were given for the page's host, a feed hosted elsewhere
+	// (FeedBurner, a CDN) would receive them on every fetch
+	if !creds.isEmpty() && !sameHost(rawURL, feedURL) {
+		return "", fmt.Errorf("%s advertises a feed on another host (%s), add that feed directly if it needs credentials", rawURL, feedURL)
+	}
+
+	feed, err := fetchForDiscovery(ctx, client, feedURL, creds)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch discovered feed: %w", err)
+	}
+	if err := checkFeed(feed.contentType, feed.body); err != nil {
+		return "", fmt.Errorf("discovered feed %s is not a feed: %w", feedURL, err)
+	}
+	return feedURL, nil
+}
+
+type discoveryResponse struct {
+	url         string // After redirects
+	contentType string
+	body        []byte
+}
+
+// fetchForDiscovery downloads a page or feed while a feed is being added.
+func fetchForDiscovery(ctx context.Context, client *feedClient, rawURL string, creds *feedCredentials) (discoveryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return discoveryResponse{}, fmt.Errorf("couldn't create request: %w", err)
+	}
+	req = creds.apply(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return discoveryResponse{}, fmt.Errorf("couldn't make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return discoveryResponse{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return discoveryResponse{}, fmt.Errorf("couldn't read response body: %w", err)
+	}
+	return discoveryResponse{url: resp.Request.URL.String(), contentType: resp.Header.Get("Content-Type"), body: body}, nil
+}
+
+// checkFeed makes sure a response is a feed we can parse, so that a URL
+// which never parses isn't stored.
+func checkFeed(contentType string, body []byte) error {
+	body, err := decodeToUTF8(contentType, body)
+	if err != nil {
+		return err
+	}
+	if !isJSONFeed(contentType, body) && detectFeedFormat(body) == feedFormatUnknown {
+		return errors.New("not an RSS, Atom, RDF or JSON feed")
+	}
+	_, err = parseFeed(contentType, body)
+	return err
+}
+
+// chooseFeed lists the discovered feeds and reads the user's pick from in.
+func chooseFeed(feeds []discoveredFeed, in io.Reader) (string, error) {
+	fmt.Println("This page advertises several feeds:")
+	for i, feed := range feeds {
+		title := feed.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Printf("  %d) %s [%s] %s\n", i+1, title, feed.Type, feed.URL)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Printf("Choose a feed (1-%d): ", len(feeds))
+		line, err := reader.ReadString('\n')
+		choice, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && choice >= 1 && choice <= len(feeds) {
+			return feeds[choice-1].URL, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no feed selected")
+		}
+		fmt.Println("Invalid choice.")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/Ernestlph/Blog_Aggregator/internal/readability"
+)
+
+// How many article pages are downloaded per feed fetch. Each one waits its
+// turn with the host limiter, so a large first fetch would otherwise keep a
+// worker busy for minutes; the rest are picked up by later fetches.
+const fullTextPerFetch = 5
+
+// How often a page that fails to download is tried before it is given up on
+const maxFullTextAttempts = 5
+
+// errNoArticlePage marks pages that will never yield an article, such as
+// missing pages or PDFs, as opposed to failures worth retrying.
+var errNoArticlePage = errors.New("page has no article")
+
+// saveFullTexts extracts the article of the newest posts of feed that don't
+// have one yet.
+func saveFullTexts(ctx context.Context, s *state, feed database.Feed) {
+	posts, err := s.db.GetPostsMissingFullText(ctx, database.GetPostsMissingFullTextParams{
+		FeedID:           feed.ID,
+		FullTextAttempts: maxFullTextAttempts,
+		Limit:            fullTextPerFetch,
+	})
+	if err != nil {
+		fmt.Printf("Error getting posts without full text for feed %s: %v\n", feed.Name, err)
+		return
+	}
+	for _, post := range posts {
+		saveFullText(ctx, s, post)
+	}
+}
+
+// saveFullText downloads the page of a post and stores the article
+// extracted from it. Pages without an article get an empty full text so they
+// aren't tried again; other failures, like timeouts or a busy server, are
+// retried on later fetches up to maxFullTextAttempts times. Either way the
+// post keeps whatever the feed gave us.
+func saveFullText(ctx context.Context, s *state, post database.Post) {
+	article := ""
+	if post.Url != "" {
+		var err error
+		article, err = fetchArticle(ctx, s.client, post.Url)
+		if err != nil {
+			fmt.Printf("Error fetching full text of %s: %v\n", post.Url, err)
+			if !errors.Is(err, errNoArticlePage) && !errors.Is(err, readability.ErrNoArticle) {
+				if err := s.db.RecordFullTextAttempt(ctx, post.ID); err != nil {
+					fmt.Printf("Error recording full text attempt for %s: %v\n", post.Url, err)
+				}
+				return
+			}
+		}
+	}
+	err := s.db.SetPostFullText(ctx, database.SetPostFullTextParams{
+		ID:       post.ID,
+		FullText: sql.NullString{String: article, Valid: true},
+	})
+	if err != nil {
+		fmt.Printf("Error saving full text of %s: %v\n", post.Url, err)
+	}
+}
+
+// fetchArticle downloads an HTML page and returns its main article, with
+// links and images pointing at absolute URLs. The feed's credentials are
+// not sent, since posts often live on another host.
+func fetchArticle(ctx context.Context, client *feedClient, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return "", fmt.Errorf("%w: status code %d", errNoArticlePage, resp.StatusCode)
+	default:
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !strings.Contains(mediaType, "html") {
+		return "", fmt.Errorf("%w: page is %s, not html", errNoArticlePage, mediaType)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read page: %w", err)
+	}
+	body, err = decodeToUTF8(contentType, body)
+	if err != nil {
+		return "", err
+	}
+
+	article, err := readability.Extract(string(body))
+	if err != nil {
+		return "", err
+	}
+	return resolveHTMLURLs(resp.Request.URL, article), nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// identity returns the key that identifies an item within its feed: the RSS
+// guid, Atom id, JSON Feed id or RDF about. Items without one are keyed by
+// their link, so a corrected title is recorded as a revision rather than a
+// new post. Items with neither are keyed by a hash of their content, since
+// feeds without links tend to reuse titles like "Daily digest"; editing such
+// an item makes it a new post.
+func (item RSSItem) identity() string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	fields := []string{item.Title, item.PubDate, item.Description, item.Content}
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Author      *JSONFeedAuthor  `json:"author"` // JSON Feed 1.0
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            JSONFeedID           `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+	Author        *JSONFeedAuthor      `json:"author"` // JSON Feed 1.0
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Tags          []string             `json:"tags"`
+}
+
+// JSONFeedID is an item id. The spec asks readers to accept ids that aren't
+// strings, as some publishers emit numbers, so anything else is kept as its
+// JSON text.
+type JSONFeedID string
+
+func (id *JSONFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = JSONFeedID(s)
+		return nil
+	}
+	if raw := strings.TrimSpace(string(data)); raw != "null" {
+		*id = JSONFeedID(raw)
+	}
+	return nil
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// jsonFeedAuthorNames returns the author names from both the 1.0 and 1.1 fields.
+func jsonFeedAuthorNames(author *JSONFeedAuthor, authors []JSONFeedAuthor) []string {
+	var names []string
+	if author != nil {
+		names = append(names, author.Name)
+	}
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// isJSONFeed reports whether the response looks like a JSON Feed, either by
+// its declared content type or by the body starting with a JSON object.
+func isJSONFeed(contentType string, body []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "application/feed+json") || strings.Contains(contentType, "application/json") {
+		return true
+	}
+	trimmed := strings.TrimSpace(string(body[:min(len(body), 512)]))
+	return strings.HasPrefix(trimmed, "{")
+}
+
+// parseJSONFeed decodes a JSON Feed (1.0 or 1.1) document, rejecting JSON
+// that doesn't declare a jsonfeed.org version. Version 1.1's authors list
+// is read alongside 1.0's single author.
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	var jsonFeed JSONFeed
+	if err := json.Unmarshal(body, &jsonFeed); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal json feed: %w", err)
+	}
+	if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("not a json feed: unexpected version %q", jsonFeed.Version)
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = jsonFeed.Title
+	feed.Channel.Link = jsonFeed.HomePageURL
+	feed.Channel.Description = jsonFeed.Description
+
+	for _, entry := range jsonFeed.Items {
+		item := RSSItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			Description: entry.Summary,
+			Content:     entry.ContentHTML,
+			GUID:        string(entry.ID),
+			PubDate:     entry.DatePublished,
+		}
+		if item.Link == "" {
+			item.Link = entry.ExternalURL
+		}
+		// Items are only required to have an id, which is often the permalink
+		if item.Link == "" && strings.HasPrefix(string(entry.ID), "http") {
+			item.Link = string(entry.ID)
+		}
+		if item.Content == "" {
+			item.Content = entry.ContentText
+		}
+		if item.Description == "" {
+			item.Description = item.Content
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+		item.Creators = jsonFeedAuthorNames(entry.Author, entry.Authors)
+		if len(item.Creators) == 0 {
+			item.Creators = jsonFeedAuthorNames(jsonFeed.Author, jsonFeed.Authors)
+		}
+		item.Categories = entry.Tags
+		for _, attachment := range entry.Attachments {
+			item.Enclosures = append(item.Enclosures, RSSEnclosure{
+				URL:    attachment.URL,
+				Type:   attachment.MimeType,
+				Length: strconv.FormatInt(attachment.SizeInBytes, 10),
+			})
+			if attachment.DurationInSeconds > 0 && item.ITunesDuration == "" {
+				item.ITunesDuration = strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64)
+			}
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// MediaContent is a Media RSS <media:content> element.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// MediaThumbnail is a Media RSS <media:thumbnail> element.
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// MediaGroup bundles alternative renditions, as YouTube feeds do.
+type MediaGroup struct {
+	Content   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+const (
+	enclosureKindEnclosure = "enclosure"
+	enclosureKindMedia     = "media"
+	enclosureKindThumbnail = "thumbnail"
+)
+
+// postEnclosure is a media file attached to an item, in the shape stored in
+// post_enclosures.
+type postEnclosure struct {
+	Kind            string
+	URL             string
+	MimeType        string
+	LengthBytes     int64 // Zero when unknown
+	DurationSeconds int32 // Zero when unknown
+}
+
+// enclosures collects every attachment of an item, dropping duplicate URLs.
+// The itunes:duration of the episode is applied to the enclosures that do
+// not carry their own duration.
+func (item RSSItem) enclosures() []postEnclosure {
+	var result []postEnclosure
+	seen := map[string]bool{}
+	add := func(enclosure postEnclosure) {
+		enclosure.URL = strings.TrimSpace(enclosure.URL)
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			return
+		}
+		seen[enclosure.URL] = true
+		result = append(result, enclosure)
+	}
+
+	for _, enclosure := range item.Enclosures {
+		add(postEnclosure{
+			Kind:        enclosureKindEnclosure,
+			URL:         enclosure.URL,
+			MimeType:    strings.TrimSpace(enclosure.Type),
+			LengthBytes: parseInt64(enclosure.Length),
+		})
+	}
+
+	contents := item.MediaContent
+	thumbnails := item.MediaThumbnail
+	for _, group := range item.MediaGroups {
+		contents = append(contents, group.Content...)
+		thumbnails = append(thumbnails, group.Thumbnail...)
+	}
+	for _, content := range contents {
+		mimeType := strings.TrimSpace(content.Type)
+		if mimeType == "" {
+			mimeType = strings.TrimSpace(content.Medium) // e.g. "audio", better than nothing
+		}
+		add(postEnclosure{
+			Kind:            enclosureKindMedia,
+			URL:             content.URL,
+			MimeType:        mimeType,
+			LengthBytes:     parseInt64(content.FileSize),
+			DurationSeconds: parseDurationSeconds(content.Duration),
+		})
+	}
+	for _, thumbnail := range thumbnails {
+		add(postEnclosure{Kind: enclosureKindThumbnail, URL: thumbnail.URL})
+	}
+
+	if duration := parseDurationSeconds(item.ITunesDuration); duration > 0 {
+		for i := range result {
+			if result[i].Kind != enclosureKindThumbnail && result[i].DurationSeconds == 0 {
+				result[i].DurationSeconds = duration
+			}
+		}
+	}
+	return result
+}
+
+// saveEnclosures stores the attachments of an item for a post. Enclosures that
+// were already stored for the post are left alone.
+func saveEnclosures(ctx context.Context, s *state, postID uuid.UUID, item RSSItem) {
+	for _, enclosure := range item.enclosures() {
+		err := s.db.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+			ID:              uuid.New(),
+			CreatedAt:       time.Now().UTC(),
+			PostID:          postID,
+			Kind:            enclosure.Kind,
+			Url:             enclosure.URL,
+			MimeType:        sql.NullString{String: enclosure.MimeType, Valid: enclosure.MimeType != ""},
+			LengthBytes:     sql.NullInt64{Int64: enclosure.LengthBytes, Valid: enclosure.LengthBytes > 0},
+			DurationSeconds: sql.NullInt32{Int32: enclosure.DurationSeconds, Valid: enclosure.DurationSeconds > 0},
+		})
+		if err != nil {
+			fmt.Printf("Error saving enclosure %s: %v\n", enclosure.URL, err)
+		}
+	}
+}
+
+// describeEnclosure formats an enclosure for browse, e.g.
+// "https://example.com/ep1.mp3 (audio/mpeg, 24.5 MB, 1:02:03)".
+func describeEnclosure(enclosure database.PostEnclosure) string {
+	var details []string
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.LengthBytes.Valid {
+		details = append(details, formatSize(enclosure.LengthBytes.Int64))
+	}
+	if enclosure.DurationSeconds.Valid {
+		details = append(details, formatDuration(enclosure.DurationSeconds.Int32))
+	}
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
+
+func enclosureLabel(kind string) string {
+	switch kind {
+	case enclosureKindMedia:
+		return "Media"
+	case enclosureKindThumbnail:
+		return "Thumbnail"
+	}
+	return "Enclosure"
+}
+
+func parseInt64(value string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseDurationSeconds reads itunes:duration style values: plain seconds,
+// "MM:SS" or "HH:MM:SS".
+func parseDurationSeconds(value string) int32 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	var total float64
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return int32(total)
+}
+
+// formatDuration renders seconds as H:MM:SS or M:SS.
+func formatDuration(seconds int32) string {
+	h, m, sec := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+// formatSize renders a byte count in decimal units.
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1e9:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/1e9)
+	case bytes >= 1e6:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/1e6)
+	case bytes >= 1e3:
+		return fmt.Sprintf("%.1f kB", float64(bytes)/1e3)
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// authors returns the distinct author names of an item. RSS <author> holds
+// an email address, usually followed by the name in parentheses, while
+// dc:creator and the other formats hold plain names.
+func (item RSSItem) authors() []string {
+	names := []string{rssAuthorName(item.Author)}
+	names = append(names, item.Creators...)
+	return distinctNames(names)
+}
+
+// categories returns the distinct category names of an item.
+func (item RSSItem) categories() []string {
+	return distinctNames(item.Categories)
+}
+
+// rssAuthorName turns "jane@example.com (Jane Doe)" into "Jane Doe". Bare
+// addresses are kept as they are, since that is all we know.
+func rssAuthorName(author string) string {
+	author = strings.TrimSpace(author)
+	open := strings.Index(author, "(")
+	if open != -1 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			return name
+		}
+	}
+	return author
+}
+
+// distinctNames trims names and drops empty ones and case-insensitive
+// duplicates, keeping the first spelling.
+func distinctNames(names []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// saveAuthorsAndCategories links a post to the authors and categories of
+// its item, creating them the first time they are seen.
+func saveAuthorsAndCategories(ctx context.Context, s *state, postID uuid.UUID, item RSSItem) {
+	for _, name := range item.authors() {
+		author, err := s.db.UpsertAuthor(ctx, database.UpsertAuthorParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			Name:      name,
+		})
+		if err != nil {
+			fmt.Printf("Error saving author %s: %v\n", name, err)
+			continue
+		}
+		err = s.db.AddPostAuthor(ctx, database.AddPostAuthorParams{PostID: postID, AuthorID: author.ID})
+		if err != nil {
+			fmt.Printf("Error linking author %s: %v\n", name, err)
+		}
+	}
+
+	for _, name := range item.categories() {
+		category, err := s.db.UpsertCategory(ctx, database.UpsertCategoryParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			Name:      name,
+		})
+		if err != nil {
+			fmt.Printf("Error saving category %s: %v\n", name, err)
+			continue
+		}
+		err = s.db.AddPostCategory(ctx, database.AddPostCategoryParams{PostID: postID, CategoryID: category.ID})
+		if err != nil {
+			fmt.Printf("Error linking category %s: %v\n", name, err)
+		}
+	}
+}
+
+// postMetadata formats the authors and categories of a post for display.
+func postMetadata(ctx context.Context, s *state, postID uuid.UUID) (authors, categories string, err error) {
+	authorRows, err := s.db.GetAuthorsForPost(ctx, postID)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't get authors: %w", err)
+	}
+	categoryRows, err := s.db.GetCategoriesForPost(ctx, postID)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't get categories: %w", err)
+	}
+
+	var names []string
+	for _, author := range authorRows {
+		names = append(names, author.Name)
+	}
+	authors = strings.Join(names, ", ")
+	names = nil
+	for _, category := range categoryRows {
+		names = append(names, category.Name)
+	}
+	categories = strings.Join(names, ", ")
+	return authors, categories, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type feedFormat int
+
+const (
+	feedFormatUnknown feedFormat = iota
+	feedFormatRSS
+	feedFormatAtom
+	feedFormatRDF
+)
+
+// detectFeedFormat looks at the root element of the document to decide which
+// parser to use.
+func detectFeedFormat(body []byte) feedFormat {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil // The body was already converted to UTF-8
+	}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return feedFormatUnknown
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case start.Name.Local == "feed" && (start.Name.Space == atomNamespace || start.Name.Space == ""):
+			return feedFormatAtom
+		case start.Name.Local == "rss":
+			return feedFormatRSS
+		case start.Name.Local == "RDF" && start.Name.Space == rdfNamespace:
+			return feedFormatRDF
+		default:
+			return feedFormatUnknown
+		}
+	}
+}
+
+// parseFeed decodes any supported feed format into an RSSFeed. The response
+// content type is used as a hint for formats that are not XML.
+//
+// Every format is mapped onto the RSS 2.0 shape so that scrapeFeeds stores
+// items the same way whatever the source. Atom and JSON Feed make some item
+// fields optional that RSS readers expect, so their parsers fill them in: an
+// item without a summary uses its content, and one without an author
+// inherits the feed's.
+func parseFeed(contentType string, body []byte) (*RSSFeed, error) {
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
+
+	switch detectFeedFormat(body) {
+	case feedFormatAtom:
+		return parseAtom(body)
+	case feedFormatRDF:
+		return parseRDF(body)
+	default:
+		// Unknown documents are decoded as RSS 2.0, as they always have been
+		return parseRSS(body)
+	}
+}
+
+func parseRSS(body []byte) (*RSSFeed, error) {
+	var feed RSSFeed
+	if err := unmarshalXML(body, &feed); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+	}
+	// Items may only have a permalink guid instead of a link
+	for i, item := range feed.Channel.Item {
+		if item.Link == "" && strings.HasPrefix(item.GUID, "http") {
+			feed.Channel.Item[i].Link = strings.TrimSpace(item.GUID)
+		}
+	}
+	return &feed, nil
+}
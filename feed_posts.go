@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltoken"
+	"github.com/google/uuid"
+)
+
+// savePost stores an item as a post of feed. Items seen before are only
+// written when their title, link or text changed, in which case the stored
+// version is kept as a revision first. Posts keyed by their link, because
+// they were stored before GUIDs were tracked or have none, are also found
+// under the link's http/https and trailing slash spellings. It reports
+// whether anything was written.
+func savePost(ctx context.Context, s *state, feedID uuid.UUID, item RSSItem, publishedAt time.Time) (database.Post, bool, error) {
+	now := time.Now().UTC()
+	params := database.UpsertPostParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: sql.NullString{String: item.Description, Valid: true},
+		Content:     sql.NullString{String: strings.TrimSpace(item.Content), Valid: strings.TrimSpace(item.Content) != ""},
+		Guid:        item.identity(),
+		PublishedAt: publishedAt,
+		FeedID:      feedID,
+	}
+
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Post{}, false, fmt.Errorf("couldn't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	existing, err := qtx.GetPostByGUID(ctx, database.GetPostByGUIDParams{FeedID: feedID, Guid: params.Guid})
+	if errors.Is(err, sql.ErrNoRows) && item.Link != "" {
+		existing, err = qtx.AdoptPostByURL(ctx, database.AdoptPostByURLParams{
+			Guid:   params.Guid,
+			FeedID: feedID,
+			Urls:   s.urls.variants(item.Link),
+		})
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// New item, nothing to compare with
+	case err != nil:
+		return database.Post{}, false, fmt.Errorf("couldn't look up post: %w", err)
+	case unchangedPost(s, existing, params):
+		if !existing.Content.Valid && params.Content.Valid {
+			err = qtx.FillPostContent(ctx, database.FillPostContentParams{ID: existing.ID, Content: params.Content})
+			if err != nil {
+				return database.Post{}, false, fmt.Errorf("couldn't save post content: %w", err)
+			}
+		}
+		// Commit anyway so an adopted post keeps its new GUID
+		if err := tx.Commit(); err != nil {
+			return database.Post{}, false, fmt.Errorf("couldn't commit post: %w", err)
+		}
+		return existing, false, nil
+	default:
+		err = qtx.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			PostID:      existing.ID,
+			Title:       existing.Title,
+			Url:         existing.Url,
+			Description: existing.Description,
+			Content:     existing.Content,
+		})
+		if err != nil {
+			return database.Post{}, false, fmt.Errorf("couldn't save previous version: %w", err)
+		}
+	}
+
+	post, err := qtx.UpsertPost(ctx, params)
+	if err != nil {
+		return database.Post{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return database.Post{}, false, fmt.Errorf("couldn't commit post: %w", err)
+	}
+	return post, true, nil
+}
+
+// unchangedPost reports whether an item matches the stored post. Posts may
+// have been stored before links were canonicalized, relative URLs resolved
+// or content kept, so links match any spelling of the same URL, HTML is
+// compared by its text alone, and missing stored content matches anything.
+func unchangedPost(s *state, stored database.Post, incoming database.UpsertPostParams) bool {
+	if stored.Title != incoming.Title || !slices.Contains(s.urls.variants(incoming.Url), s.urls.canonical(stored.Url)) {
+		return false
+	}
+	if htmlText(stored.Description.String) != htmlText(incoming.Description.String) {
+		return false
+	}
+	return !stored.Content.Valid || htmlText(stored.Content.String) == htmlText(incoming.Content.String)
+}
+
+// htmlText returns the text of an HTML fragment with whitespace collapsed,
+// leaving out tags and attributes.
+func htmlText(fragment string) string {
+	var words []string
+	for _, tok := range htmltoken.Tokenize(fragment) {
+		if tok.Type == htmltoken.TextToken {
+			words = append(words, strings.Fields(tok.Data)...)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	rdfNamespace        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+)
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0 the items are siblings of
+// the channel element rather than children of it.
+type RDFFeed struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	Base        string   `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+// parseRDF decodes an RSS 1.0 (RDF) document. Items sit next to the channel
+// rather than inside it, and dates and authors come from Dublin Core.
+func parseRDF(body []byte) (*RSSFeed, error) {
+	var rdf RDFFeed
+	if err := unmarshalXML(body, &rdf); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal rdf feed: %w", err)
+	}
+
+	feed := &RSSFeed{Base: rdf.Base}
+	feed.Channel.Title = strings.TrimSpace(rdf.Channel.Title)
+	feed.Channel.Link = strings.TrimSpace(rdf.Channel.Link)
+	feed.Channel.Description = strings.TrimSpace(rdf.Channel.Description)
+	feed.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+
+	for _, entry := range rdf.Item {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(entry.Title),
+			Link:        strings.TrimSpace(entry.Link),
+			GUID:        strings.TrimSpace(entry.About),
+			Base:        entry.Base,
+			Description: strings.TrimSpace(entry.Description),
+			Content:     strings.TrimSpace(entry.Content),
+			Creators:    entry.Creators,
+			Categories:  entry.Subjects,
+			PubDate:     strings.TrimSpace(entry.Date), // dc:date is W3C-DTF, a profile of RFC 3339
+		})
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// permanentRedirectTarget walks the redirect chain that produced resp and
+// returns the URL reached from the original request through permanent (301
+// or 308) redirects only. It returns "" when the first hop was temporary or
+// there was no redirect.
+func permanentRedirectTarget(resp *http.Response) string {
+	// Collect the requests from the final one back to the original
+	var chain []*http.Request
+	for req := resp.Request; req != nil; {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+
+	target := ""
+	for i := len(chain) - 1; i > 0; i-- {
+		// chain[i-1] was created by the redirect response to chain[i]
+		status := chain[i-1].Response.StatusCode
+		if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+			break
+		}
+		target = chain[i-1].URL.String()
+	}
+	return target
+}
+
+// applyPermanentRedirect moves a feed to newURL. When another feed already
+// uses that URL the two are merged: follows and posts move to the existing
+// feed and the redirected one is deleted. It returns the feed that now owns
+// the URL.
+func applyPermanentRedirect(ctx context.Context, s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return feed, fmt.Errorf("couldn't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	survivor := feed
+	merged := false
+
+	// Another spelling of the feed's own URL (e.g. http to https) is a move,
+	// not a merge
+	candidates := slices.DeleteFunc(s.urls.variants(newURL), func(u string) bool { return u == feed.Url })
+	existing, err := qtx.GetFeedByURL(ctx, candidates)
+	switch {
+	case err == sql.ErrNoRows:
+		err = qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{ID: feed.ID, Url: newURL})
+		if err != nil {
+			return feed, fmt.Errorf("couldn't update feed url: %w", err)
+		}
+		survivor.Url = newURL
+	case err != nil:
+		return feed, fmt.Errorf("couldn't check for existing feed: %w", err)
+	default:
+		err = qtx.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: existing.ID, FromFeedID: feed.ID})
+		if err != nil {
+			return feed, fmt.Errorf("couldn't move feed follows: %w", err)
+		}
+		err = qtx.MovePostsToFeed(ctx, database.MovePostsToFeedParams{ToFeedID: existing.ID, FromFeedID: feed.ID})
+		if err != nil {
+			return feed, fmt.Errorf("couldn't move posts: %w", err)
+		}
+		if err := qtx.DeleteFeed(ctx, feed.ID); err != nil {
+			return feed, fmt.Errorf("couldn't delete redirected feed: %w", err)
+		}
+		survivor = existing
+		merged = true
+	}
+
+	// Keep a record so the owner can see why their feed changed
+	err = qtx.CreateFeedURLChange(ctx, database.CreateFeedURLChangeParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		FeedID:    uuid.NullUUID{UUID: survivor.ID, Valid: true},
+		UserID:    feed.UserID,
+		OldUrl:    feed.Url,
+		NewUrl:    newURL,
+		Merged:    merged,
+	})
+	if err != nil {
+		return feed, fmt.Errorf("couldn't record url change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return feed, fmt.Errorf("couldn't commit url change: %w", err)
+	}
+
+	if merged {
+		fmt.Printf("Feed %s moved permanently to %s, merged into existing feed %s\n", feed.Name, newURL, survivor.Name)
+	} else {
+		fmt.Printf("Feed %s moved permanently from %s to %s\n", feed.Name, feed.Url, newURL)
+	}
+	return survivor, nil
+}
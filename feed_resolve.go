@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltoken"
+)
+
+// Attributes holding a single URL that are rewritten in item HTML
+var htmlURLAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+	"cite":   true,
+}
+
+// resolveRelativeURLs makes the item links, attachments and the URLs inside
+// item HTML absolute. Each item resolves against its own xml:base, then the
+// feed's xml:base, then the channel link, and finally feedURL itself.
+func resolveRelativeURLs(feed *RSSFeed, feedURL string) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return
+	}
+
+	feed.Channel.Link = resolveURL(base, feed.Channel.Link)
+	docBase := base
+	switch {
+	case feed.Base != "" || feed.Channel.Base != "":
+		docBase = resolveBase(resolveBase(base, feed.Base), feed.Channel.Base)
+	case feed.Channel.Link != "":
+		docBase = resolveBase(base, feed.Channel.Link)
+	}
+
+	for i := range feed.Channel.Item {
+		resolveItemURLs(&feed.Channel.Item[i], docBase)
+	}
+}
+
+func resolveItemURLs(item *RSSItem, docBase *url.URL) {
+	itemBase := resolveBase(docBase, item.Base)
+	item.Link = resolveURL(itemBase, item.Link)
+	item.Description = resolveHTMLURLs(itemBase, item.Description)
+	item.Content = resolveHTMLURLs(itemBase, item.Content)
+	for j := range item.Enclosures {
+		item.Enclosures[j].URL = resolveURL(itemBase, item.Enclosures[j].URL)
+	}
+	resolveMedia(itemBase, item.MediaContent, item.MediaThumbnail)
+	for _, group := range item.MediaGroups {
+		resolveMedia(itemBase, group.Content, group.Thumbnail)
+	}
+}
+
+func resolveMedia(base *url.URL, contents []MediaContent, thumbnails []MediaThumbnail) {
+	for i := range contents {
+		contents[i].URL = resolveURL(base, contents[i].URL)
+	}
+	for i := range thumbnails {
+		thumbnails[i].URL = resolveURL(base, thumbnails[i].URL)
+	}
+}
+
+// resolveBase applies a (possibly relative) xml:base or link to base,
+// keeping base when ref is empty or invalid.
+func resolveBase(base *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return base
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(u)
+}
+
+// resolveURL returns ref made absolute against base. Empty, absolute and
+// unparsable references are returned unchanged.
+func resolveURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" {
+		return ref
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// resolveHTMLURLs rewrites the link and image URLs in an HTML fragment to be
+// absolute. Tags without relative URLs are left byte for byte as they were.
+func resolveHTMLURLs(base *url.URL, fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return fragment
+	}
+
+	var b strings.Builder
+	for _, tok := range htmltoken.Tokenize(fragment) {
+		if tok.Type != htmltoken.StartTagToken && tok.Type != htmltoken.SelfClosingTagToken {
+			b.WriteString(tok.Raw)
+			continue
+		}
+		changed := false
+		for i, attr := range tok.Attr {
+			resolved := attr.Val
+			switch {
+			case htmlURLAttributes[attr.Key]:
+				resolved = resolveURL(base, attr.Val)
+			case attr.Key == "srcset":
+				resolved = resolveSrcset(base, attr.Val)
+			}
+			if resolved != attr.Val {
+				tok.Attr[i].Val = resolved
+				changed = true
+			}
+		}
+		if changed {
+			b.WriteString(tok.String())
+		} else {
+			b.WriteString(tok.Raw)
+		}
+	}
+	return b.String()
+}
+
+// resolveSrcset resolves each candidate of an img srcset, e.g.
+// "a.jpg 1x, b.jpg 2x".
+func resolveSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	changed := false
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if resolved := resolveURL(base, fields[0]); resolved != fields[0] {
+			fields[0] = resolved
+			candidates[i] = strings.Join(fields, " ")
+			changed = true
+		}
+	}
+	if !changed {
+		return srcset
+	}
+	return strings.Join(candidates, ",")
+}
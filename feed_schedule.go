@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+)
+
+const (
+	minFetchInterval = 15 * time.Minute
+	maxFetchInterval = 24 * time.Hour
+
+	// Number of recent posts used to estimate how often a feed publishes
+	scheduleSampleSize = 10
+
+	// Upper bound on how long a publisher hint can make us wait
+	maxPublisherInterval = 7 * 24 * time.Hour
+)
+
+// publisherInterval turns the channel's ttl and sy:updatePeriod /
+// sy:updateFrequency hints into the minimum time between polls. Zero means
+// the publisher gave no usable hint.
+func publisherInterval(feed *RSSFeed) time.Duration {
+	var interval time.Duration
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL)); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+
+	periods := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+		"yearly":  365 * 24 * time.Hour,
+	}
+	if period, ok := periods[strings.ToLower(strings.TrimSpace(feed.Channel.UpdatePeriod))]; ok {
+		frequency, err := strconv.Atoi(strings.TrimSpace(feed.Channel.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1 // The syndication module default
+		}
+		if syInterval := period / time.Duration(frequency); syInterval > interval {
+			interval = syInterval
+		}
+	}
+
+	return min(interval, maxPublisherInterval)
+}
+
+// skipHoursAndDays normalises the skipHours (0-23, GMT) and skipDays
+// (Monday..Sunday) channel elements, dropping invalid values.
+func skipHoursAndDays(feed *RSSFeed) ([]int32, []string) {
+	hours := []int32{}
+	for _, value := range feed.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		hours = append(hours, int32(hour%24)) // Some feeds use 24 for midnight
+	}
+
+	days := []string{}
+	for _, value := range feed.Channel.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(value), weekday.String()) {
+				days = append(days, weekday.String())
+			}
+		}
+	}
+	return hours, days
+}
+
+// avoidSkippedTimes moves t forward hour by hour until it falls outside the
+// feed's skipHours and skipDays, which are expressed in GMT.
+func avoidSkippedTimes(t time.Time, skipHours []int32, skipDays []string) time.Time {
+	t = t.UTC()
+	for i := 0; i < 7*24; i++ {
+		if !slices.Contains(skipHours, int32(t.Hour())) && !slices.Contains(skipDays, t.Weekday().String()) {
+			return t
+		}
+		t = t.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t // Every hour is skipped, which we treat as no restriction
+}
+
+// savePollingHints stores the publisher's polling hints from a freshly
+// fetched feed and returns the feed row with the new values applied.
+func savePollingHints(ctx context.Context, s *state, feed database.Feed, rssFeed *RSSFeed) database.Feed {
+	interval := publisherInterval(rssFeed)
+	skipHours, skipDays := skipHoursAndDays(rssFeed)
+
+	feed.MinPollMinutes = sql.NullInt32{Int32: int32(interval / time.Minute), Valid: interval > 0}
+	feed.SkipHours = skipHours
+	feed.SkipDays = skipDays
+
+	err := s.db.UpdateFeedPollingHints(ctx, database.UpdateFeedPollingHintsParams{
+		ID:             feed.ID,
+		MinPollMinutes: feed.MinPollMinutes,
+		SkipHours:      feed.SkipHours,
+		SkipDays:       feed.SkipDays,
+	})
+	if err != nil {
+		fmt.Printf("Error saving polling hints for feed %s: %v\n", feed.Name, err)
+	}
+	return feed
+}
+
+// nextFetchInterval estimates how long to wait before polling a feed again
+// from the publish dates of its most recent posts (newest first). We poll
+// about twice per observed gap between posts, and slow down as the feed goes
+// quiet for longer than its usual gap.
+func nextFetchInterval(now time.Time, published []time.Time) time.Duration {
+	if len(published) < 2 {
+		return maxFetchInterval
+	}
+
+	newest := published[0]
+	oldest := published[len(published)-1]
+	averageGap := newest.Sub(oldest) / time.Duration(len(published)-1)
+
+	gap := averageGap
+	if sinceNewest := now.Sub(newest); sinceNewest > gap {
+		gap = sinceNewest
+	}
+
+	return clampDuration(gap/2, minFetchInterval, maxFetchInterval)
+}
+
+// failureBackoff doubles the wait after each consecutive failure, starting
+// from minFetchInterval and capped at maxFetchInterval.
+func failureBackoff(failures int32) time.Duration {
+	backoff := minFetchInterval
+	for i := int32(1); i < failures && backoff < maxFetchInterval; i++ {
+		backoff *= 2
+	}
+	return clampDuration(backoff, minFetchInterval, maxFetchInterval)
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	if d < lo {
+		return lo
+	}
+	if d > hi {
+		return hi
+	}
+	return d
+}
+
+// recordFeedSuccess clears the failure state of a feed after a successful
+// fetch and schedules its next poll.
+func recordFeedSuccess(ctx context.Context, s *state, feed database.Feed) {
+	if err := s.db.RecordFeedSuccess(ctx, feed.ID); err != nil {
+		fmt.Printf("Error recording success for feed %s: %v\n", feed.Name, err)
+	}
+	scheduleNextFetch(ctx, s, feed)
+}
+
+// recordFeedFailure stores the fetch error, backs the feed off exponentially
+// and disables it once it reaches the configured failure limit. A server
+// that asks us to come back later with Retry-After is only obeyed, since
+// that is a publisher asking us to slow down rather than a broken feed.
+func recordFeedFailure(ctx context.Context, s *state, feed database.Feed, fetchErr error) {
+	var retryErr *retryAfterError
+	if errors.As(fetchErr, &retryErr) && retryErr.RetryAfter > 0 {
+		nextFetch := avoidSkippedTimes(time.Now().Add(max(retryErr.RetryAfter, minFetchInterval)), feed.SkipHours, feed.SkipDays)
+		err := s.db.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+			ID:          feed.ID,
+			NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+		})
+		if err != nil {
+			fmt.Printf("Error scheduling next fetch for feed %s: %v\n", feed.Name, err)
+			return
+		}
+		fmt.Printf("Feed %s asked us to come back at %s\n", feed.Name, nextFetch.Format(time.RFC3339))
+		return
+	}
+
+	backoff := failureBackoff(feed.ConsecutiveFailures + 1)
+	nextFetch := avoidSkippedTimes(time.Now().Add(backoff), feed.SkipHours, feed.SkipDays)
+	failures, err := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ID:          feed.ID,
+		LastError:   sql.NullString{String: fetchErr.Error(), Valid: true},
+		NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+	})
+	if err != nil {
+		fmt.Printf("Error recording failure for feed %s: %v\n", feed.Name, err)
+		return
+	}
+
+	if int(failures) >= s.cfg.FeedFailureLimit() {
+		if err := s.db.DisableFeed(ctx, feed.ID); err != nil {
+			fmt.Printf("Error disabling feed %s: %v\n", feed.Name, err)
+			return
+		}
+		fmt.Printf("Feed %s disabled after %d consecutive failures\n", feed.Name, failures)
+		return
+	}
+	fmt.Printf("Feed %s failed %d time(s) in a row, retrying in %s\n", feed.Name, failures, backoff)
+}
+
+// scheduleNextFetch stores when the feed is next due based on its posting
+// frequency.
+func scheduleNextFetch(ctx context.Context, s *state, feed database.Feed) {
+	published, err := s.db.GetRecentPostDatesForFeed(ctx, database.GetRecentPostDatesForFeedParams{
+		FeedID: feed.ID,
+		Limit:  scheduleSampleSize,
+	})
+	if err != nil {
+		fmt.Printf("Error getting recent posts for feed %s: %v\n", feed.Name, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	interval := nextFetchInterval(now, published)
+	// Respect the publisher's ttl / sy:updatePeriod even for busy feeds
+	if feed.MinPollMinutes.Valid {
+		interval = max(interval, time.Duration(feed.MinPollMinutes.Int32)*time.Minute)
+	}
+	nextFetch := avoidSkippedTimes(now.Add(interval), feed.SkipHours, feed.SkipDays)
+
+	err = s.db.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+	})
+	if err != nil {
+		fmt.Printf("Error scheduling next fetch for feed %s: %v\n", feed.Name, err)
+		return
+	}
+	fmt.Printf("Feed %s next fetch at %s\n", feed.Name, nextFetch.Format(time.RFC3339))
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/config"
+)
+
+const (
+	defaultFetchTimeout      = 30 * time.Second
+	defaultFetchMaxBodyBytes = 10 << 20 // 10 MiB
+	defaultFetchUserAgent    = "gator"
+
+	defaultHostRequestsPerMinute = 30
+	defaultHostMaxConnections    = 2
+)
+
+// fetchSettings are the resolved settings used for a single request.
+type fetchSettings struct {
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	UserAgent    string
+	Proxy        *url.URL
+}
+
+// feedClient performs all outgoing feed requests. It applies the timeout,
+// body size limit, User-Agent and proxy from the fetch config, including the
+// per-feed overrides.
+type feedClient struct {
+	defaults  fetchSettings
+	overrides map[string]fetchSettings
+	policy    *urlPolicy
+	limiter   *hostLimiter
+	robots    *robotsCache // nil unless robots.txt checking is enabled
+
+	mu      sync.Mutex
+	clients map[string]*http.Client // Keyed by timeout and proxy
+}
+
+func newFeedClient(cfg config.FetchConfig) (*feedClient, error) {
+	base := fetchSettings{
+		Timeout:      defaultFetchTimeout,
+		MaxBodyBytes: defaultFetchMaxBodyBytes,
+		UserAgent:    defaultFetchUserAgent,
+	}
+	defaults, err := applyFetchSettings(base, cfg.FetchSettings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch config: %w", err)
+	}
+
+	overrides := make(map[string]fetchSettings, len(cfg.Feeds))
+	for feedURL, override := range cfg.Feeds {
+		settings, err := applyFetchSettings(defaults, override)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fetch config for %s: %w", feedURL, err)
+		}
+		overrides[feedURL] = settings
+	}
+
+	policy, err := newURLPolicy(cfg.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch config: %w", err)
+	}
+
+	requestsPerMinute := cfg.HostRequestsPerMinute
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = defaultHostRequestsPerMinute
+	}
+	maxConnections := cfg.HostMaxConnections
+	if maxConnections <= 0 {
+		maxConnections = defaultHostMaxConnections
+	}
+
+	client := &feedClient{
+		defaults:  defaults,
+		overrides: overrides,
+		policy:    policy,
+		limiter:   newHostLimiter(requestsPerMinute, maxConnections),
+		clients:   make(map[string]*http.Client),
+	}
+	if cfg.RespectRobots {
+		client.robots = newRobotsCache()
+	}
+	return client, nil
+}
+
+// applyFetchSettings layers the non-zero values of raw on top of base.
+func applyFetchSettings(base fetchSettings, raw config.FetchSettings) (fetchSettings, error) {
+	if raw.Timeout != "" {
+		timeout, err := time.ParseDuration(raw.Timeout)
+		if err != nil {
+			return fetchSettings{}, fmt.Errorf("could not parse timeout: %w", err)
+		}
+		base.Timeout = timeout
+	}
+	if raw.MaxBodyBytes > 0 {
+		base.MaxBodyBytes = raw.MaxBodyBytes
+	}
+	if raw.UserAgent != "" {
+		base.UserAgent = raw.UserAgent
+	}
+	if raw.Proxy != "" {
+		proxyURL, err := url.Parse(raw.Proxy)
+		if err != nil {
+			return fetchSettings{}, fmt.Errorf("could not parse proxy: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fetchSettings{}, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		base.Proxy = proxyURL
+	}
+	return base, nil
+}
+
+// settingsFor returns the settings for a feed URL, falling back to the
+// global ones when there is no override.
+func (c *feedClient) settingsFor(feedURL string) fetchSettings {
+	if settings, ok := c.overrides[feedURL]; ok {
+		return settings
+	}
+	return c.defaults
+}
+
+func (c *feedClient) httpClient(settings fetchSettings) *http.Client {
+	key := settings.Timeout.String()
+	if settings.Proxy != nil {
+		key += " " + settings.Proxy.String()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		return client
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	proxyHosts := envProxyHosts()
+	if settings.Proxy != nil {
+		transport.Proxy = http.ProxyURL(settings.Proxy)
+		proxyHosts = []string{strings.ToLower(settings.Proxy.Hostname())}
+	}
+	transport.DialContext = c.policy.dialContext(proxyHosts)
+
+	client := &http.Client{
+		Timeout:   settings.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			stripCredentialHeaders(req, via[0])
+			return c.checkDestination(req, settings)
+		},
+	}
+	c.clients[key] = client
+	return client
+}
+
+// checkURL applies the URL policy to a URL before it is stored or fetched.
+func (c *feedClient) checkURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	return c.policy.checkURL(ctx, u)
+}
+
+// checkDestination validates a request or redirect target. Direct
+// connections have their resolved address checked when dialing, but through
+// a proxy we never see it, so the host is resolved and checked up front.
+func (c *feedClient) checkDestination(req *http.Request, settings fetchSettings) error {
+	proxyURL := settings.Proxy
+	if proxyURL == nil {
+		envProxy, err := http.ProxyFromEnvironment(req)
+		if err != nil {
+			return err
+		}
+		proxyURL = envProxy
+	}
+	if proxyURL != nil {
+		return c.policy.checkURL(req.Context(), req.URL)
+	}
+	return c.policy.checkScheme(req.URL)
+}
+
+// Do sends req with the settings for its URL. The response body errors with
+// *http.MaxBytesError once it grows past the configured size limit.
+func (c *feedClient) Do(req *http.Request) (*http.Response, error) {
+	settings := c.settingsFor(req.URL.String())
+	if err := c.checkDestination(req, settings); err != nil {
+		return nil, err
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", settings.UserAgent)
+	}
+
+	// Wait for our turn on this host; the slot is held until the body is closed
+	release, err := c.limiter.wait(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := c.httpClient(settings)
+	if c.robots != nil && !c.robots.allowed(req.Context(), httpClient, req.URL, req.Header.Get("User-Agent")) {
+		release()
+		return nil, fmt.Errorf("fetching %s is disallowed by robots.txt", req.URL)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{
+		ReadCloser: http.MaxBytesReader(nil, resp.Body, settings.MaxBodyBytes),
+		release:    release,
+	}
+	return resp, nil
+}
+
+// releasingBody frees the host limiter slot when the response is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
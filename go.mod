@@ -3,6 +3,11 @@ module github.com/Ernestlph/Blog_Aggregator
 go 1.23.0
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.43.0
+	golang.org/x/term v0.34.0
+	golang.org/x/text v0.28.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"reflect"
 	"time"
@@ -12,14 +13,63 @@ import (
 
 func handlerAddFeed(s *state, cmd command, user database.User) error { // Modified handler signature
 	// 1. Input validation
-	if len(cmd.Args) != 2 {
-		return fmt.Errorf("usage: %s <name> <url>", cmd.Name)
+	creds, args, err := parseCredentialArgs(cmd.Args)
+	if err != nil {
+		return err
+	}
+	fullText := false
+	var positional []string
+	for _, arg := range args {
+		if arg == "--full-text" {
+			fullText = true
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	args = positional
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s <name> <url> [--basic <user:password>] [--bearer <token>] [--header <\"Name: value\">] [--full-text]", cmd.Name)
+	}
+	name := args[0]
+
+	// Fail before creating anything if the credentials can't be stored
+	if !creds.isEmpty() {
+		if _, err := credentialsKey(s); err != nil {
+			return err
+		}
 	}
-	name := cmd.Args[0]
-	url := cmd.Args[1]
 
 	// 2. Get current user (No longer needed - user is passed by middleware)
 
+	// 2a. Refuse URLs that point at internal services
+	rawURL := s.urls.canonical(args[1])
+	if err := s.client.checkURL(context.Background(), rawURL); err != nil {
+		return fmt.Errorf("feed url not allowed: %w", err)
+	}
+
+	// 2b. If the URL is a web page, store the feed it advertises instead
+	url, err := resolveFeedURL(context.Background(), s.client, rawURL, creds)
+	if err != nil {
+		return fmt.Errorf("couldn't check feed url: %w", err)
+	}
+	url = s.urls.canonical(url)
+
+	// The page may advertise a feed somewhere we must not fetch from
+	if url != rawURL {
+		if err := s.client.checkURL(context.Background(), url); err != nil {
+			return fmt.Errorf("feed url %s not allowed: %w", url, err)
+		}
+	}
+
+	// 2c. The same feed may already be stored under another spelling
+	existing, err := s.db.GetFeedByURL(context.Background(), s.urls.variants(url))
+	if err == nil {
+		return fmt.Errorf("feed %s already exists as %q, use follow to follow it", existing.Url, existing.Name)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("couldn't check for existing feed: %w", err)
+	}
+
 	// 3. Create feed with proper error handling
 	feed, err := s.db.CreateFeed(context.Background(),
 		database.CreateFeedParams{
@@ -34,7 +84,33 @@ func handlerAddFeed(s *state, cmd command, user database.User) error { // Modifi
 	if err != nil {
 		return fmt.Errorf("failed to create feed: %w", err)
 	}
-	_ = feed
+
+	// 3a. Store the credentials encrypted for the new feed
+	if !creds.isEmpty() {
+		sealed, err := encryptCredentials(s, feed, creds)
+		if err != nil {
+			return err
+		}
+		err = s.db.SetFeedCredentials(context.Background(), database.SetFeedCredentialsParams{
+			ID:          feed.ID,
+			Credentials: sealed,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't save feed credentials: %w", err)
+		}
+		fmt.Printf("Credentials saved: %s\n", creds.describe())
+	}
+
+	// 3b. Download and extract the article of each new post
+	if fullText {
+		err = s.db.SetFeedFetchFullText(context.Background(), database.SetFeedFetchFullTextParams{
+			ID:            feed.ID,
+			FetchFullText: true,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't enable full text: %w", err)
+		}
+	}
 
 	// 4. Get and verify the created feed
 	showfeed, err := s.db.GetFeed(context.Background(), name)
@@ -53,6 +129,11 @@ func handlerAddFeed(s *state, cmd command, user database.User) error { // Modifi
 		fieldName := t.Field(i).Name
 		fieldValue := v.Field(i).Interface()
 
+		// Never print the (encrypted) credentials
+		if fieldName == "Credentials" {
+			continue
+		}
+
 		if fieldValue == "" {
 			fmt.Printf("Warning: Field %s is empty\n", fieldName)
 		} else {
@@ -3,24 +3,32 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/xml"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Ernestlph/Blog_Aggregator/internal/database"
-	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 type RSSFeed struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"` // Relative URLs in the feed resolve against this
 	Channel struct {
+		Base        string    `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
+
+		// Publisher polling hints
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	} `xml:"channel"`
 }
 
@@ -29,6 +37,23 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"` // Identifies the item within its feed, see identity
+	Base        string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+
+	// Full article body, most feeds only put a teaser in description
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+
+	// Who wrote the item and what it is about
+	Author     string   `xml:"author"`
+	Creators   []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories []string `xml:"category"`
+
+	// Podcast and media attachments
+	Enclosures     []RSSEnclosure   `xml:"enclosure"`
+	MediaContent   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups    []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	ITunesDuration string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
 }
 
 func parseTime(dateStr string) (time.Time, error) {
@@ -37,6 +62,7 @@ func parseTime(dateStr string) (time.Time, error) {
 		time.RFC1123Z,
 		time.RFC3339,
 		time.RFC3339Nano,
+		"2006-01-02T15:04Z07:00", // W3C-DTF without seconds, seen in dc:date
 		time.RFC822,
 		time.RFC822Z,
 		time.RFC850,
@@ -60,8 +86,8 @@ func parseTime(dateStr string) (time.Time, error) {
 }
 
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <time_between_reqs>", cmd.Name)
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return fmt.Errorf("usage: %s <time_between_reqs> <optional concurrency>", cmd.Name)
 	}
 
 	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
@@ -69,49 +95,160 @@ func handlerAgg(s *state, cmd command) error {
 		return fmt.Errorf("could not parse duration: %w", err)
 	}
 
-	fmt.Printf("Collecting feeds every %s...\n", timeBetweenRequests)
+	concurrency := 1 // Default to fetching one feed at a time
+	if len(cmd.Args) == 2 {
+		concurrency, err = strconv.Atoi(cmd.Args[1])
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("invalid concurrency: %v", cmd.Args[1])
+		}
+	}
+
+	fmt.Printf("Collecting up to %d feeds every %s...\n", concurrency, timeBetweenRequests)
+
+	// Each token lets one worker scrape one feed. The buffer caps the backlog
+	// at one round, so at most `concurrency` fetches start per tick and slow
+	// feeds never pile up extra work.
+	tokens := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for range tokens {
+				scrapeFeeds(s)
+			}
+		}()
+	}
+
+	dispatch := func() {
+		for i := 0; i < concurrency; i++ {
+			select {
+			case tokens <- struct{}{}:
+			default: // All workers already have work queued
+			}
+		}
+	}
 
 	ticker := time.NewTicker(timeBetweenRequests)
 
-	scrapeFeeds(s) // Run immediately when agg command starts
+	dispatch() // Run immediately when agg command starts
 
 	for range ticker.C { // Run every time the ticker ticks
-		scrapeFeeds(s)
+		dispatch()
 	}
 	return nil // This part of the code will likely not be reached in a ticker loop
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// fetchResult is the outcome of a successful fetch. Feed is nil when the
+// server answered 304 Not Modified to our conditional request.
+type fetchResult struct {
+	Feed         *RSSFeed
+	ETag         string
+	LastModified string
+	PermanentURL string // Set when the feed was permanently redirected
+}
+
+// retryAfterError is returned when the server asks us to back off with a
+// 429 or 503 response.
+type retryAfterError struct {
+	StatusCode int
+	RetryAfter time.Duration // Zero when the server did not say how long
+}
+
+func (e *retryAfterError) Error() string {
+	if e.RetryAfter == 0 {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d (retry after %s)", e.StatusCode, e.RetryAfter)
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date. Like publisher hints it is capped at
+// maxPublisherInterval, so a bogus value can't park a feed for years.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		// Cap before multiplying so huge values can't overflow
+		return time.Duration(min(seconds, int(maxPublisherInterval/time.Second))) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return min(date.Sub(now), maxPublisherInterval)
+	}
+	return 0
+}
+
+func fetchFeed(ctx context.Context, client *feedClient, dbFeed database.Feed, creds *feedCredentials) (fetchResult, error) {
 	// Create a new request with NewRequestWithContext
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", dbFeed.Url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create request: %w", err)
+		return fetchResult{}, fmt.Errorf("couldn't create request: %w", err)
 	}
-	// Set a user agent header
-	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	req = creds.apply(req) // Authentication for private feeds, if any
 
-	// Create a http client and make request
-	resp, err := http.DefaultClient.Do(req)
+	// Send the validators from the last fetch so unchanged feeds cost a 304
+	if dbFeed.Etag.Valid {
+		req.Header.Set("If-None-Match", dbFeed.Etag.String)
+	}
+	if dbFeed.LastModified.Valid {
+		req.Header.Set("If-Modified-Since", dbFeed.LastModified.String)
+	}
+
+	// Make the request with the configured timeout, size limit and proxy
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't make request: %w", err)
+		return fetchResult{}, fmt.Errorf("couldn't make request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	result := fetchResult{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if target := permanentRedirectTarget(resp); target != "" && target != dbFeed.Url {
+		result.PermanentURL = target
+	}
+
+	// Nothing changed since the last fetch, keep the stored validators
+	// unless the server sent fresh ones with the 304
+	if resp.StatusCode == http.StatusNotModified {
+		if result.ETag == "" {
+			result.ETag = dbFeed.Etag.String
+		}
+		if result.LastModified == "" {
+			result.LastModified = dbFeed.LastModified.String
+		}
+		return result, nil
+	}
+
+	// The publisher is asking us to slow down
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return fetchResult{}, &retryAfterError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
 	// Check response status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fetchResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	// Decode response body
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't read response body: %w", err)
+		return fetchResult{}, fmt.Errorf("couldn't read response body: %w", err)
 	}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+	body, err = decodeToUTF8(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return fetchResult{}, err
+	}
+
+	feed, err := parseFeed(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return fetchResult{}, err
 	}
 	// Unescape HTML entities for the channel
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
@@ -123,6 +260,9 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
 	}
 
+	// Relative links are relative to where the feed ended up after redirects
+	resolveRelativeURLs(feed, resp.Request.URL.String())
+
 	// For debugging: Print the entire struct
 	fmt.Printf("Feed Title: %s\nDescription: %s\nLink: %s\n",
 		feed.Channel.Title, feed.Channel.Description, feed.Channel.Link)
@@ -131,39 +271,75 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 			item.Title, item.Description, item.Link, item.PubDate)
 	}
 
-	return &feed, err
+	result.Feed = feed
+	return result, nil
 }
 
 func scrapeFeeds(s *state) {
 	ctx := context.Background()
 
 	fmt.Println("Fetching next feed...")
-	feedRow, err := s.db.GetNextFeedToFetch(ctx)
+	feedRow, err := s.db.GetNextFeedToFetch(ctx) // Atomically claims the feed
 	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Println("No feeds to fetch at the moment.")
+			return
+		}
 		fmt.Printf("Error getting next feed to fetch: %v\n", err)
 		return
 	}
 
-	if feedRow.ID.String() == "" {
-		fmt.Println("No feeds to fetch at the moment.")
-		return
-	}
-
 	fmt.Printf("Fetching feed: %s from %s\n", feedRow.Name, feedRow.Url)
-	err = s.db.MarkFeedFetched(ctx, feedRow.ID)
+
+	// A bug hit by one feed must not stop the aggregator. Counting it as a
+	// failed fetch backs the feed off and eventually disables it.
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("panic while scraping: %v", r)
+			fmt.Printf("Error scraping feed %s: %v\n", feedRow.Name, err)
+			recordFeedFailure(ctx, s, feedRow, err)
+		}
+	}()
+
+	creds, err := decryptCredentials(s, feedRow)
 	if err != nil {
-		fmt.Printf("Error marking feed as fetched: %v\n", err)
+		fmt.Printf("Error loading feed credentials: %v\n", err)
+		recordFeedFailure(ctx, s, feedRow, err)
 		return
 	}
 
-	rssFeed, err := fetchFeed(ctx, feedRow.Url)
+	result, err := fetchFeed(ctx, s.client, feedRow, creds)
 	if err != nil {
 		fmt.Printf("Error fetching feed content: %v\n", err)
+		recordFeedFailure(ctx, s, feedRow, err)
+		return
+	}
+
+	// Follow permanent redirects in the stored URL
+	if newURL := s.urls.canonical(result.PermanentURL); newURL != "" && newURL != feedRow.Url {
+		moved, err := applyPermanentRedirect(ctx, s, feedRow, newURL)
+		if err != nil {
+			fmt.Printf("Error updating url of feed %s: %v\n", feedRow.Name, err)
+		} else {
+			feedRow = moved
+		}
+	}
+
+	if result.Feed == nil {
+		if feedRow.FetchFullText {
+			saveFullTexts(ctx, s, feedRow) // Catch up on posts left from earlier fetches
+		}
+		saveCacheValidators(ctx, s, feedRow, result)
+		recordFeedSuccess(ctx, s, feedRow)
+		fmt.Printf("Feed %s not modified since last fetch\n----------------------\n", feedRow.Name)
 		return
 	}
+	rssFeed := result.Feed
+	feedRow = savePollingHints(ctx, s, feedRow, rssFeed)
 
 	fmt.Printf("Feed fetched, saving posts...\n") // Indicate saving posts
 
+	saveFailed := false
 	for _, item := range rssFeed.Channel.Item {
 		publishedAt := time.Now().UTC() // Default to now if parsing fails
 		if item.PubDate != "" {
@@ -175,22 +351,43 @@ func scrapeFeeds(s *state) {
 			}
 		}
 
-		_, err := s.db.CreatePost(ctx, database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: sql.NullString{String: item.Description, Valid: true}, // Convert item.Description to sql.NullString
-			PublishedAt: publishedAt,
-			FeedID:      feedRow.ID,
-		})
+		// Tracking parameters and fragments would make the same article look new
+		item.Link = s.urls.canonical(item.Link)
+
+		post, saved, err := savePost(ctx, s, feedRow.ID, item, publishedAt)
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-				continue // Ignore duplicate URL errors
-			}
-			fmt.Printf("Error creating post: %v\n", err) // Log other errors
+			fmt.Printf("Error saving post: %v\n", err)
+			saveFailed = true
+			continue
 		}
+		if !saved {
+			continue // Already stored and unchanged
+		}
+		if post.RevisedAt.Valid {
+			fmt.Printf("Post updated: %s\n", post.Title)
+		}
+		saveEnclosures(ctx, s, post.ID, item)
+		saveAuthorsAndCategories(ctx, s, post.ID, item)
+	}
+	if feedRow.FetchFullText {
+		saveFullTexts(ctx, s, feedRow)
+	}
+	// Only store the validators once the posts are saved, otherwise a later 304
+	// would hide the items we failed to store
+	if !saveFailed {
+		saveCacheValidators(ctx, s, feedRow, result)
 	}
+	recordFeedSuccess(ctx, s, feedRow)
 	fmt.Printf("Feed %s posts saved!\n----------------------\n", feedRow.Name) // Indicate posts saved
 }
+
+func saveCacheValidators(ctx context.Context, s *state, feed database.Feed, result fetchResult) {
+	err := s.db.UpdateFeedCacheValidators(ctx, database.UpdateFeedCacheValidatorsParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+	})
+	if err != nil {
+		fmt.Printf("Error saving cache validators for feed %s: %v\n", feed.Name, err)
+	}
+}
@@ -2,50 +2,110 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltext"
 )
 
+// How much of each description browse shows
+const browseDescriptionRunes = 300
+
 func handlerBrowse(s *state, cmd command, user database.User) error {
 	limit := 2 // Default limit
+	usage := fmt.Errorf("usage: %s <optional limit> [--author <name>] [--tag <name>]", cmd.Name)
 
-	if len(cmd.Args) > 1 {
-		return fmt.Errorf("usage: %s <optional limit>", cmd.Name)
+	// Pull out the filters, whatever is left is the limit
+	var author, tag sql.NullString
+	var rest []string
+	for i := 0; i < len(cmd.Args); i++ {
+		flag := cmd.Args[i]
+		if flag != "--author" && flag != "--tag" {
+			rest = append(rest, flag)
+			continue
+		}
+		if i+1 >= len(cmd.Args) {
+			return fmt.Errorf("%s needs a value", flag)
+		}
+		value := sql.NullString{String: cmd.Args[i+1], Valid: true}
+		i++
+		if flag == "--author" {
+			author = value
+		} else {
+			tag = value
+		}
 	}
-	if len(cmd.Args) == 1 {
-		providedLimit, err := strconv.Atoi(cmd.Args[0])
+
+	if len(rest) > 1 {
+		return usage
+	}
+	if len(rest) == 1 {
+		providedLimit, err := strconv.Atoi(rest[0])
 		if err != nil {
-			return fmt.Errorf("invalid limit: %v", cmd.Args[0])
+			return fmt.Errorf("invalid limit: %v", rest[0])
 		}
 		limit = providedLimit
 	}
 
 	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit), // Convert limit to int32 as expected by sqlc
+		UserID:   user.ID,
+		Author:   author,
+		Category: tag,
+		Limit:    int32(limit), // Convert limit to int32 as expected by sqlc
 	})
 	if err != nil {
 		return fmt.Errorf("couldn't get posts: %w", err)
 	}
 
+	width := terminalWidth()
 	fmt.Println("Posts for you:")
 	if len(posts) == 0 {
 		fmt.Println("  No posts found from feeds you follow.")
 	} else {
 		for _, post := range posts {
-			fmt.Printf("  - Title: %s\n", post.Title)
+			if post.RevisedAt.Valid {
+				fmt.Printf("  - Title: %s (updated)\n", post.Title)
+			} else {
+				fmt.Printf("  - Title: %s\n", post.Title)
+			}
 			fmt.Printf("    URL: %s\n", post.Url)
 			fmt.Printf("    Published At: %s\n", post.PublishedAt.Format(time.RFC3339)) // Format time for display
-			if post.Description.Valid {                                                 // Check if description is valid (not NULL)
-				description := post.Description.String // Access the string value
-				// Truncate description if it's too long for display
-				if len(description) > 200 {
-					description = description[:200] + "..."
+			if post.RevisedAt.Valid {
+				fmt.Printf("    Updated At: %s\n", post.RevisedAt.Time.Format(time.RFC3339))
+			}
+			authors, categories, err := postMetadata(context.Background(), s, post.ID)
+			if err != nil {
+				return fmt.Errorf("couldn't get details of post %s: %w", post.Url, err)
+			}
+			if authors != "" {
+				fmt.Printf("    Author: %s\n", authors)
+			}
+			if categories != "" {
+				fmt.Printf("    Tags: %s\n", categories)
+			}
+			if post.Description.Valid { // Check if description is valid (not NULL)
+				// Render the HTML as text and keep it short for display
+				description := htmltext.Render(post.Description.String, htmltext.Options{
+					Width:    width,
+					Indent:   "      ",
+					MaxRunes: browseDescriptionRunes,
+				})
+				if description != "" {
+					fmt.Printf("    Description:\n%s\n", description)
 				}
-				fmt.Printf("    Description: %s\n", description)
+			}
+			if post.Content.Valid || post.FullText.String != "" {
+				fmt.Printf("    Full article: gator read %s\n", post.Url)
+			}
+			enclosures, err := s.db.GetEnclosuresForPost(context.Background(), post.ID)
+			if err != nil {
+				return fmt.Errorf("couldn't get enclosures for post %s: %w", post.Url, err)
+			}
+			for _, enclosure := range enclosures {
+				fmt.Printf("    %s: %s\n", enclosureLabel(enclosure.Kind), describeEnclosure(enclosure))
 			}
 			fmt.Println() // Add an empty line between posts
 		}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+)
+
+func handlerEditFeed(s *state, cmd command, user database.User) error {
+	creds, args, err := parseCredentialArgs(cmd.Args)
+	if err != nil {
+		return err
+	}
+
+	clearAuth := false
+	var fullText *bool
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--clear-auth":
+			clearAuth = true
+		case "--full-text":
+			if i+1 >= len(args) || (args[i+1] != "on" && args[i+1] != "off") {
+				return fmt.Errorf("--full-text must be followed by on or off")
+			}
+			enabled := args[i+1] == "on"
+			fullText = &enabled
+			i++
+		default:
+			positional = append(positional, args[i])
+		}
+	}
+	if len(positional) != 1 || (creds.isEmpty() && !clearAuth && fullText == nil) {
+		return fmt.Errorf("usage: %s <feed_url> [--basic <user:password>] [--bearer <token>] [--header <\"Name: value\">] [--clear-auth] [--full-text on|off]", cmd.Name)
+	}
+	if clearAuth && !creds.isEmpty() {
+		return fmt.Errorf("--clear-auth can't be combined with new credentials")
+	}
+
+	feedURL := positional[0]
+	ctx := context.Background()
+
+	feed, err := s.db.GetFeedByURL(ctx, s.urls.variants(feedURL))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed %v does not exist", feedURL)
+		}
+		return fmt.Errorf("couldn't get feed: %w", err)
+	}
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only the user who added feed %s can edit it", feed.Name)
+	}
+
+	// New credentials replace the stored ones entirely
+	if !creds.isEmpty() || clearAuth {
+		sealed, err := encryptCredentials(s, feed, creds)
+		if err != nil {
+			return err
+		}
+		err = s.db.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+			ID:          feed.ID,
+			Credentials: sealed,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't save feed credentials: %w", err)
+		}
+		fmt.Printf("Feed %s credentials: %s\n", feed.Name, creds.describe())
+	}
+
+	if fullText != nil {
+		err = s.db.SetFeedFetchFullText(ctx, database.SetFeedFetchFullTextParams{
+			ID:            feed.ID,
+			FetchFullText: *fullText,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't save full text setting: %w", err)
+		}
+		setting := "off"
+		if *fullText {
+			setting = "on"
+		}
+		fmt.Printf("Feed %s full text: %s\n", feed.Name, setting)
+	}
+	return nil
+}
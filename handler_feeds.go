@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/google/uuid"
 )
 
 func handlerFeeds(s *state, cmd command) error {
@@ -27,7 +32,64 @@ func handlerFeeds(s *state, cmd command) error {
 		}
 		fmt.Printf("%s: %s Added by: %s\n", feed.Name, feed.Url, user.Name)
 
+		// Show the health of the feed so broken ones stand out
+		if feed.DisabledAt.Valid {
+			fmt.Printf("    Status: disabled since %s\n", feed.DisabledAt.Time.Format(time.RFC3339))
+		} else if feed.ConsecutiveFailures > 0 {
+			fmt.Printf("    Status: failing (%d consecutive failures)\n", feed.ConsecutiveFailures)
+		}
+		if feed.LastError.Valid {
+			fmt.Printf("    Last error: %s\n", feed.LastError.String)
+		}
+		if feed.LastSuccessAt.Valid {
+			fmt.Printf("    Last success: %s\n", feed.LastSuccessAt.Time.Format(time.RFC3339))
+		}
+		if feed.FetchFullText {
+			fmt.Println("    Full text: on")
+		}
+
+		// Show URL changes made after permanent redirects
+		changes, err := s.db.GetFeedURLChanges(context.Background(), uuid.NullUUID{UUID: feed.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			note := ""
+			if change.Merged {
+				note = " (merged)"
+			}
+			fmt.Printf("    Moved from %s on %s%s\n", change.OldUrl, change.CreatedAt.Format(time.RFC3339), note)
+		}
+
+	}
+
+	return nil
+}
+
+func handlerEnableFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <feed_url>", cmd.Name)
+	}
+
+	feedURL := cmd.Args[0]
+	ctx := context.Background()
+
+	feed, err := s.db.GetFeedByURL(ctx, s.urls.variants(feedURL))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed %v does not exist", feedURL)
+		}
+		return fmt.Errorf("couldn't get feed: %w", err)
+	}
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only the user who added feed %s can enable it", feed.Name)
+	}
+
+	// Clears the failure count and makes the feed due immediately
+	if err := s.db.EnableFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("couldn't enable feed: %w", err)
 	}
 
+	fmt.Printf("Feed %s re-enabled by %s\n", feed.Name, user.Name)
 	return nil
 }
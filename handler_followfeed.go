@@ -18,7 +18,7 @@ func handlerFollowFeed(s *state, cmd command, user database.User) error { // Mod
 	ctx := context.Background()
 
 	// Verify feed exists
-	feed, err := s.db.GetFeedByURL(ctx, feedURL)
+	feed, err := s.db.GetFeedByURL(ctx, s.urls.variants(feedURL))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("feed %v does not exist", feedURL)
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/database"
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltext"
+)
+
+// handlerRead prints the full article of a post from a followed feed,
+// falling back to the description when the feed only publishes a teaser.
+func handlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <post_url>", cmd.Name)
+	}
+	ctx := context.Background()
+
+	post, err := s.db.GetPostForUserByURL(ctx, database.GetPostForUserByURLParams{
+		UserID: user.ID,
+		Urls:   s.urls.variants(cmd.Args[0]),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no post with url %s in the feeds you follow", cmd.Args[0])
+		}
+		return fmt.Errorf("couldn't get post: %w", err)
+	}
+
+	fmt.Printf("%s\n", post.Title)
+	fmt.Printf("URL: %s\n", post.Url)
+	fmt.Printf("Published At: %s\n", post.PublishedAt.Format(time.RFC3339))
+	if post.RevisedAt.Valid {
+		revisions, err := s.db.GetPostRevisions(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get revisions of post %s: %w", post.Url, err)
+		}
+		fmt.Printf("Updated At: %s\n", post.RevisedAt.Time.Format(time.RFC3339))
+		fmt.Printf("Earlier versions: %d\n", len(revisions))
+		for _, revision := range revisions {
+			if revision.Title != post.Title {
+				fmt.Printf("  Previously titled %q until %s\n", revision.Title, revision.CreatedAt.Format(time.RFC3339))
+			}
+		}
+	}
+	authors, categories, err := postMetadata(ctx, s, post.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get details of post %s: %w", post.Url, err)
+	}
+	if authors != "" {
+		fmt.Printf("Author: %s\n", authors)
+	}
+	if categories != "" {
+		fmt.Printf("Tags: %s\n", categories)
+	}
+
+	enclosures, err := s.db.GetEnclosuresForPost(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get enclosures for post %s: %w", post.Url, err)
+	}
+	for _, enclosure := range enclosures {
+		fmt.Printf("%s: %s\n", enclosureLabel(enclosure.Kind), describeEnclosure(enclosure))
+	}
+	fmt.Println()
+
+	text := ""
+	switch {
+	case post.FullText.String != "":
+		text = post.FullText.String
+	case post.Content.Valid:
+		text = post.Content.String
+	case post.Description.Valid:
+		text = post.Description.String
+	}
+	text = htmltext.Render(text, htmltext.Options{Width: terminalWidth()})
+	if text == "" {
+		text = "This post has no content, open the URL to read it."
+	}
+	fmt.Println(text)
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/Ernestlph/Blog_Aggregator/internal/database"
@@ -15,14 +16,23 @@ func handlerUnfollowFeed(s *state, cmd command, user database.User) error {
 	feedURL := cmd.Args[0]
 	ctx := context.Background()
 
-	err := s.db.DeleteFeedFollowByUserAndFeedURL(ctx, database.DeleteFeedFollowByUserAndFeedURLParams{
+	// Find the feed under whichever spelling of the URL it was stored
+	feed, err := s.db.GetFeedByURL(ctx, s.urls.variants(feedURL))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed %v does not exist", feedURL)
+		}
+		return fmt.Errorf("couldn't get feed: %w", err)
+	}
+
+	err = s.db.DeleteFeedFollowByUserAndFeedURL(ctx, database.DeleteFeedFollowByUserAndFeedURLParams{
 		UserID: user.ID, // Assuming 'user' is the logged-in user object
-		Url:    feedURL,
+		Url:    feed.Url,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to unfollow feed: %w", err)
 	}
 
-	fmt.Printf("User %s is no longer following feed with URL: %s\n", user.Name, feedURL)
+	fmt.Printf("User %s is no longer following feed with URL: %s\n", user.Name, feed.Url)
 	return nil
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out requests to the same host and caps how many run at
+// once, so feeds that share a host are not fetched in bursts.
+type hostLimiter struct {
+	interval       time.Duration // Minimum time between request starts, zero for no limit
+	maxConnections int           // Zero for no limit
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots    chan struct{}
+	nextSlot time.Time
+}
+
+func newHostLimiter(requestsPerMinute, maxConnections int) *hostLimiter {
+	limiter := &hostLimiter{
+		maxConnections: maxConnections,
+		hosts:          make(map[string]*hostState),
+	}
+	if requestsPerMinute > 0 {
+		limiter.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return limiter
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hs, ok := l.hosts[host]
+	if !ok {
+		hs = &hostState{}
+		if l.maxConnections > 0 {
+			hs.slots = make(chan struct{}, l.maxConnections)
+		}
+		l.hosts[host] = hs
+	}
+	return hs
+}
+
+// wait blocks until a request to host may start and returns the function
+// that frees its connection slot.
+func (l *hostLimiter) wait(ctx context.Context, host string) (func(), error) {
+	hs := l.state(strings.ToLower(host))
+
+	release := func() {}
+	if hs.slots != nil {
+		select {
+		case hs.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-hs.slots }) }
+	}
+
+	if l.interval > 0 {
+		// Reserve the next start time, then sleep until it arrives
+		l.mu.Lock()
+		now := time.Now()
+		start := hs.nextSlot
+		if start.Before(now) {
+			start = now
+		}
+		hs.nextSlot = start.Add(l.interval)
+		l.mu.Unlock()
+
+		if delay := time.Until(start); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return release, nil
+}
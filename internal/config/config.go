@@ -8,9 +8,56 @@ import (
 
 const configFileName = ".gatorconfig.json"
 
+// Default number of consecutive failed fetches before a feed is disabled
+const defaultMaxFeedFailures = 10
+
 type Config struct {
-	DBURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBURL           string      `json:"db_url"`
+	CurrentUserName string      `json:"current_user_name"`
+	MaxFeedFailures int         `json:"max_feed_failures,omitempty"`
+	CredentialsKey  string      `json:"credentials_key,omitempty"` // Base64 AES-256 key for feed credentials
+	Fetch           FetchConfig `json:"fetch"`
+	URLs            URLConfig   `json:"urls"`
+}
+
+// URLConfig controls how feed and post URLs are canonicalized.
+type URLConfig struct {
+	// Query parameters stripped from URLs, a trailing "*" matches any suffix
+	// (e.g. "utm_*"). Replaces the built-in list when set.
+	TrackingParams []string `json:"tracking_params,omitempty"`
+	// Leave tracking parameters alone entirely
+	KeepTrackingParams bool `json:"keep_tracking_params,omitempty"`
+}
+
+// FetchSettings controls how feeds are downloaded. Zero values fall back to
+// the defaults of the fetch client.
+type FetchSettings struct {
+	Timeout      string `json:"timeout,omitempty"` // Go duration, e.g. "30s"
+	MaxBodyBytes int64  `json:"max_body_bytes,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	Proxy        string `json:"proxy,omitempty"` // http://, https:// or socks5:// URL
+}
+
+// FetchConfig holds the global fetch settings plus overrides keyed by feed URL.
+type FetchConfig struct {
+	FetchSettings
+	Feeds map[string]FetchSettings `json:"feeds,omitempty"`
+	// Host names, IPs or CIDR ranges that may be fetched even though they
+	// are private or internal addresses
+	Allow []string `json:"allow,omitempty"`
+
+	// Politeness limits applied to every host
+	HostRequestsPerMinute int  `json:"host_requests_per_minute,omitempty"`
+	HostMaxConnections    int  `json:"host_max_connections,omitempty"`
+	RespectRobots         bool `json:"respect_robots,omitempty"`
+}
+
+// FeedFailureLimit returns how many consecutive failures disable a feed.
+func (cfg *Config) FeedFailureLimit() int {
+	if cfg.MaxFeedFailures <= 0 {
+		return defaultMaxFeedFailures
+	}
+	return cfg.MaxFeedFailures
 }
 
 func (cfg *Config) SetUser(userName string) error {
@@ -7,9 +7,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeed = `-- name: CreateFeed :one
@@ -22,7 +24,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_success_at, disabled_at, min_poll_minutes, skip_hours, skip_days, credentials, fetch_full_text
 `
 
 type CreateFeedParams struct {
@@ -52,12 +54,60 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.MinPollMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Credentials,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, id)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    consecutive_failures = 0,
+    next_fetch_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_success_at, disabled_at, min_poll_minutes, skip_hours, skip_days, credentials, fetch_full_text FROM feeds
 WHERE name = $1 LIMIT 1
 `
 
@@ -72,17 +122,32 @@ func (q *Queries) GetFeed(ctx context.Context, name string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.MinPollMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Credentials,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-WHERE url = $1 LIMIT 1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_success_at, disabled_at, min_poll_minutes, skip_hours, skip_days, credentials, fetch_full_text FROM feeds
+WHERE url = ANY($1::TEXT[])
+ORDER BY created_at
+LIMIT 1
 `
 
-func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+// Takes every spelling the URL may be stored under, see urlCanonicalizer
+func (q *Queries) GetFeedByURL(ctx context.Context, urls []string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, pq.Array(urls))
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -92,12 +157,24 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.MinPollMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Credentials,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_success_at, disabled_at, min_poll_minutes, skip_hours, skip_days, credentials, fetch_full_text FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -117,6 +194,18 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.MinPollMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.Credentials,
+			&i.FetchFullText,
 		); err != nil {
 			return nil, err
 		}
@@ -132,12 +221,27 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1
+UPDATE feeds
+SET last_fetched_at = NOW(),
+    next_fetch_at = NOW() + INTERVAL '15 minutes',
+    updated_at = NOW()
+WHERE id = (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_success_at, disabled_at, min_poll_minutes, skip_hours, skip_days, credentials, fetch_full_text
 `
 
+// Claims the stalest due feed by stamping last_fetched_at in the same
+// statement. SKIP LOCKED lets concurrent aggregators pass over a row another
+// one is claiming instead of both picking it, and pushing next_fetch_at out
+// leases the feed to this fetch until it schedules the real next fetch. A
+// fetch that dies without doing so frees the feed when the lease runs out.
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
 	var i Feed
@@ -149,18 +253,168 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.MinPollMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Credentials,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedFailure = `-- name: RecordFeedFailure :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING consecutive_failures
+`
+
+type RecordFeedFailureParams struct {
+	ID          uuid.UUID
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure, arg.ID, arg.LastError, arg.NextFetchAt)
+	var consecutive_failures int32
+	err := row.Scan(&consecutive_failures)
+	return consecutive_failures, err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_success_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const setFeedCredentials = `-- name: SetFeedCredentials :exec
+UPDATE feeds
+SET credentials = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedCredentialsParams struct {
+	ID          uuid.UUID
+	Credentials []byte
+}
+
+func (q *Queries) SetFeedCredentials(ctx context.Context, arg SetFeedCredentialsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCredentials, arg.ID, arg.Credentials)
+	return err
+}
+
+const setFeedFetchFullText = `-- name: SetFeedFetchFullText :exec
+UPDATE feeds
+SET fetch_full_text = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedFetchFullTextParams struct {
+	ID            uuid.UUID
+	FetchFullText bool
+}
+
+func (q *Queries) SetFeedFetchFullText(ctx context.Context, arg SetFeedFetchFullTextParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullText, arg.ID, arg.FetchFullText)
+	return err
+}
+
+const setFeedNextFetchAt = `-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedNextFetchAtParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetchAt(ctx context.Context, arg SetFeedNextFetchAtParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetchAt, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2,
+    last_modified = $3,
     updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type UpdateFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
+
+const updateFeedPollingHints = `-- name: UpdateFeedPollingHints :exec
+UPDATE feeds
+SET min_poll_minutes = $2,
+    skip_hours = $3,
+    skip_days = $4,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedPollingHintsParams struct {
+	ID             uuid.UUID
+	MinPollMinutes sql.NullInt32
+	SkipHours      []int32
+	SkipDays       []string
+}
+
+func (q *Queries) UpdateFeedPollingHints(ctx context.Context, arg UpdateFeedPollingHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedPollingHints,
+		arg.ID,
+		arg.MinPollMinutes,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
 	return err
 }
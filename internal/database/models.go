@@ -11,14 +11,38 @@ import (
 	"github.com/google/uuid"
 )
 
+type Author struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+}
+
+type Category struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	NextFetchAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	MinPollMinutes      sql.NullInt32
+	SkipHours           []int32
+	SkipDays            []string
+	Credentials         []byte
+	FetchFullText       bool
 }
 
 type FeedFollow struct {
@@ -29,15 +53,61 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FeedUrlChange struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.NullUUID
+	UserID    uuid.UUID
+	OldUrl    string
+	NewUrl    string
+	Merged    bool
+}
+
 type Post struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Title            string
+	Url              string
+	Description      sql.NullString
+	PublishedAt      time.Time
+	FeedID           uuid.UUID
+	Content          sql.NullString
+	Guid             string
+	RevisedAt        sql.NullTime
+	FullText         sql.NullString
+	FullTextAttempts int32
+}
+
+type PostAuthor struct {
+	PostID   uuid.UUID
+	AuthorID uuid.UUID
+}
+
+type PostCategory struct {
+	PostID     uuid.UUID
+	CategoryID uuid.UUID
+}
+
+type PostEnclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        sql.NullString
+	LengthBytes     sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
+type PostRevision struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	PostID      uuid.UUID
 	Title       string
 	Url         string
 	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	Content     sql.NullString
 }
 
 type User struct {
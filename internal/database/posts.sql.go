@@ -11,44 +11,82 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (
-    id,
-    created_at,
-    updated_at,
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptPostByURL = `-- name: AdoptPostByURL :one
+UPDATE posts
+SET guid = $1
+WHERE id = (
+    SELECT keyed.id FROM posts AS keyed
+    WHERE keyed.feed_id = $2
+    AND keyed.guid = ANY($3::TEXT[])
+    ORDER BY keyed.created_at
+    LIMIT 1
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, revised_at, full_text, full_text_attempts
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+type AdoptPostByURLParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Urls   []string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
+// Posts stored before GUIDs were tracked, and items without a GUID, are
+// keyed by their link. When such an item turns up under a real GUID or
+// another spelling of its link, the post takes that key over.
+func (q *Queries) AdoptPostByURL(ctx context.Context, arg AdoptPostByURLParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, adoptPostByURL, arg.Guid, arg.FeedID, pq.Array(arg.Urls))
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.FullText,
+		&i.FullTextAttempts,
 	)
+	return i, err
+}
+
+const fillPostContent = `-- name: FillPostContent :exec
+UPDATE posts
+SET content = $2
+WHERE id = $1 AND content IS NULL
+`
+
+type FillPostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+// Stores the content of a post saved before content was kept, without
+// counting it as a revision.
+func (q *Queries) FillPostContent(ctx context.Context, arg FillPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, fillPostContent, arg.ID, arg.Content)
+	return err
+}
+
+const getPostByGUID = `-- name: GetPostByGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, revised_at, full_text, full_text_attempts FROM posts
+WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByGUIDParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) GetPostByGUID(ctx context.Context, arg GetPostByGUIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGUID, arg.FeedID, arg.Guid)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -59,26 +97,85 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.FullText,
+		&i.FullTextAttempts,
+	)
+	return i, err
+}
+
+const getPostForUserByURL = `-- name: GetPostForUserByURL :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.guid, posts.revised_at, posts.full_text, posts.full_text_attempts
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1 AND posts.url = ANY($2::TEXT[])
+ORDER BY posts.published_at DESC
+LIMIT 1
+`
+
+type GetPostForUserByURLParams struct {
+	UserID uuid.UUID
+	Urls   []string
+}
+
+func (q *Queries) GetPostForUserByURL(ctx context.Context, arg GetPostForUserByURLParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUserByURL, arg.UserID, pq.Array(arg.Urls))
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.FullText,
+		&i.FullTextAttempts,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.guid, posts.revised_at, posts.full_text, posts.full_text_attempts
 FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
+AND ($2::TEXT IS NULL OR EXISTS (
+    SELECT 1 FROM post_authors
+    JOIN authors ON authors.id = post_authors.author_id
+    WHERE post_authors.post_id = posts.id AND lower(authors.name) = lower($2)
+))
+AND ($3::TEXT IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    JOIN categories ON categories.id = post_categories.category_id
+    WHERE post_categories.post_id = posts.id AND lower(categories.name) = lower($3)
+))
 ORDER BY posts.published_at DESC
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Author   sql.NullString
+	Category sql.NullString
+	Limit    int32
 }
 
+// Author and category filters are optional and match names case-insensitively
+
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +192,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.Guid,
+			&i.RevisedAt,
+			&i.FullText,
+			&i.FullTextAttempts,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +210,212 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostsMissingFullText = `-- name: GetPostsMissingFullText :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, revised_at, full_text, full_text_attempts FROM posts
+WHERE feed_id = $1 AND full_text IS NULL AND full_text_attempts < $2
+ORDER BY published_at DESC
+LIMIT $3
+`
+
+type GetPostsMissingFullTextParams struct {
+	FeedID           uuid.UUID
+	FullTextAttempts int32
+	Limit            int32
+}
+
+func (q *Queries) GetPostsMissingFullText(ctx context.Context, arg GetPostsMissingFullTextParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsMissingFullText, arg.FeedID, arg.FullTextAttempts, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Guid,
+			&i.RevisedAt,
+			&i.FullText,
+			&i.FullTextAttempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostDatesForFeed = `-- name: GetRecentPostDatesForFeed :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPostDatesForFeedParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostDatesForFeed(ctx context.Context, arg GetRecentPostDatesForFeedParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostDatesForFeed, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePostsToFeed = `-- name: MovePostsToFeed :exec
+UPDATE posts
+SET feed_id = $1,
+    updated_at = NOW()
+WHERE feed_id = $2
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = $1 AND existing.guid = posts.guid
+)
+`
+
+type MovePostsToFeedParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// Posts the target feed already has stay behind and go away with the old feed
+func (q *Queries) MovePostsToFeed(ctx context.Context, arg MovePostsToFeedParams) error {
+	_, err := q.db.ExecContext(ctx, movePostsToFeed, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFullTextAttempt = `-- name: RecordFullTextAttempt :exec
+UPDATE posts
+SET full_text_attempts = full_text_attempts + 1
+WHERE id = $1
+`
+
+func (q *Queries) RecordFullTextAttempt(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFullTextAttempt, id)
+	return err
+}
+
+const setPostFullText = `-- name: SetPostFullText :exec
+UPDATE posts
+SET full_text = $2
+WHERE id = $1
+`
+
+type SetPostFullTextParams struct {
+	ID       uuid.UUID
+	FullText sql.NullString
+}
+
+func (q *Queries) SetPostFullText(ctx context.Context, arg SetPostFullTextParams) error {
+	_, err := q.db.ExecContext(ctx, setPostFullText, arg.ID, arg.FullText)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content,
+    guid
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    updated_at = EXCLUDED.updated_at,
+    revised_at = EXCLUDED.updated_at,
+    full_text = NULL,
+    full_text_attempts = 0
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, revised_at, full_text, full_text_attempts
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+}
+
+// Inserts a new post or overwrites the stored version of an item that
+// changed. published_at keeps its original value since it is made up for
+// items without a date, and the extracted full text is dropped so it is
+// fetched again.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Content,
+		arg.Guid,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.FullText,
+		&i.FullTextAttempts,
+	)
+	return i, err
+}
@@ -0,0 +1,373 @@
+// Package htmltext renders feed HTML as plain text for the terminal. It keeps
+// the structure that matters when reading (paragraphs, headings, lists,
+// quotes and preformatted blocks), turns links into numbered footnotes and
+// wraps the result to a given width.
+package htmltext
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltoken"
+)
+
+type Options struct {
+	Width    int    // Wrap lines to this many runes, including Indent; zero disables wrapping
+	Indent   string // Written in front of every line
+	MaxRunes int    // Cut the text after this many runes, zero for no limit
+}
+
+// Elements that start a new block of text
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "tr": true, "ul": true,
+}
+
+// Elements whose content is never shown
+var hiddenElements = map[string]bool{
+	"head":     true,
+	"noscript": true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+}
+
+type block struct {
+	prefix string // First line prefix, e.g. a list bullet
+	indent string // Prefix of the following lines
+	text   string
+	pre    bool
+	inList bool
+	tight  bool // No blank line before it, used for consecutive list items
+}
+
+type list struct {
+	ordered bool
+	count   int
+}
+
+type link struct {
+	href  string
+	start int // Length of the text buffer when the link opened
+}
+
+type renderer struct {
+	blocks    []block
+	text      strings.Builder
+	lists     []list
+	links     []link
+	footnotes []string
+
+	quoteDepth int
+	preDepth   int
+	hidden     int
+
+	// Prefix waiting for the first text of a list item
+	pendingPrefix string
+}
+
+// Render converts an HTML fragment to wrapped plain text. Text without any
+// markup is treated as plain text with blank lines between paragraphs.
+func Render(src string, opts Options) string {
+	r := &renderer{}
+	if strings.Contains(src, "<") {
+		for _, tok := range htmltoken.Tokenize(src) {
+			r.token(tok)
+		}
+	} else {
+		for _, paragraph := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n\n") {
+			r.writeText(paragraph)
+			r.flush()
+		}
+	}
+	r.flush()
+
+	blocks := r.blocks
+	if opts.MaxRunes > 0 {
+		blocks = truncateBlocks(blocks, opts.MaxRunes)
+	}
+
+	var b strings.Builder
+	for i, blk := range blocks {
+		if i > 0 {
+			b.WriteString("\n")
+			if !blk.tight {
+				b.WriteString(opts.Indent + "\n")
+			}
+		}
+		b.WriteString(formatBlock(blk, opts))
+	}
+
+	// Only list the footnotes the kept text still refers to
+	var notes []string
+	for i, href := range r.footnotes {
+		marker := fmt.Sprintf("[%d]", i+1)
+		if strings.Contains(b.String(), marker) {
+			notes = append(notes, opts.Indent+marker+" "+href)
+		}
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n" + opts.Indent + "\n" + strings.Join(notes, "\n"))
+	}
+	return strings.TrimRight(b.String(), " \n")
+}
+
+func (r *renderer) token(tok htmltoken.Token) {
+	switch tok.Type {
+	case htmltoken.TextToken:
+		if r.hidden == 0 {
+			r.writeText(tok.Data)
+		}
+	case htmltoken.StartTagToken, htmltoken.SelfClosingTagToken:
+		if hiddenElements[tok.Data] {
+			if tok.Type == htmltoken.StartTagToken {
+				r.hidden++
+			}
+			return
+		}
+		if r.hidden == 0 {
+			r.startTag(tok)
+			if tok.Type == htmltoken.SelfClosingTagToken {
+				r.endTag(tok.Data)
+			}
+		}
+	case htmltoken.EndTagToken:
+		if hiddenElements[tok.Data] {
+			if r.hidden > 0 {
+				r.hidden--
+			}
+			return
+		}
+		if r.hidden == 0 {
+			r.endTag(tok.Data)
+		}
+	}
+}
+
+func (r *renderer) startTag(tok htmltoken.Token) {
+	if blockElements[tok.Data] {
+		r.flush()
+	}
+	switch tok.Data {
+	case "br":
+		r.text.WriteString("\n")
+	case "hr":
+		r.blocks = append(r.blocks, block{text: "―――"})
+	case "blockquote":
+		r.quoteDepth++
+	case "pre":
+		r.preDepth++
+	case "ul", "ol":
+		r.lists = append(r.lists, list{ordered: tok.Data == "ol"})
+	case "li":
+		depth := max(len(r.lists), 1)
+		bullet := "• "
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1].ordered {
+			r.lists[len(r.lists)-1].count++
+			bullet = fmt.Sprintf("%d. ", r.lists[len(r.lists)-1].count)
+		}
+		r.pendingPrefix = strings.Repeat("  ", depth-1) + bullet
+	case "a":
+		href, _ := tok.AttrVal("href")
+		r.links = append(r.links, link{href: strings.TrimSpace(href), start: r.text.Len()})
+	case "img":
+		if alt, _ := tok.AttrVal("alt"); strings.TrimSpace(alt) != "" {
+			r.writeText("[image: " + strings.TrimSpace(alt) + "]")
+		}
+	case "td", "th":
+		if !strings.HasSuffix(r.text.String(), "\n") && r.text.Len() > 0 {
+			r.text.WriteString(" | ")
+		}
+	}
+}
+
+func (r *renderer) endTag(name string) {
+	switch name {
+	case "a":
+		if len(r.links) == 0 {
+			return
+		}
+		l := r.links[len(r.links)-1]
+		r.links = r.links[:len(r.links)-1]
+		label := strings.TrimSpace(r.text.String()[l.start:])
+		if l.href == "" || strings.HasPrefix(l.href, "#") || strings.HasPrefix(l.href, "javascript:") || label == l.href {
+			return
+		}
+		r.footnotes = append(r.footnotes, l.href)
+		fmt.Fprintf(&r.text, "[%d]", len(r.footnotes))
+	case "blockquote":
+		r.flush()
+		if r.quoteDepth > 0 {
+			r.quoteDepth--
+		}
+	case "pre":
+		r.flush()
+		if r.preDepth > 0 {
+			r.preDepth--
+		}
+	case "ul", "ol":
+		r.flush()
+		if len(r.lists) > 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+	default:
+		if blockElements[name] {
+			r.flush()
+		}
+	}
+}
+
+func (r *renderer) writeText(text string) {
+	if r.preDepth > 0 {
+		r.text.WriteString(text)
+		return
+	}
+	// Collapse whitespace like a browser, keeping one space between words
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		if text != "" && r.text.Len() > 0 && !strings.HasSuffix(r.text.String(), " ") {
+			r.text.WriteString(" ")
+		}
+		return
+	}
+	if startsWithSpace(text) && r.text.Len() > 0 && !strings.HasSuffix(r.text.String(), " ") {
+		r.text.WriteString(" ")
+	}
+	r.text.WriteString(collapsed)
+	if endsWithSpace(text) {
+		r.text.WriteString(" ")
+	}
+}
+
+// flush turns the buffered text into a block.
+func (r *renderer) flush() {
+	text := r.text.String()
+	r.text.Reset()
+	for i := range r.links {
+		r.links[i].start = 0 // The link continues in the next block
+	}
+	if r.preDepth == 0 {
+		text = strings.TrimSpace(text)
+	} else {
+		text = strings.Trim(text, "\n")
+	}
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	quote := strings.Repeat("> ", r.quoteDepth)
+	indent := quote + strings.Repeat("  ", len(r.lists))
+	blk := block{prefix: indent, indent: indent, text: text, pre: r.preDepth > 0, inList: len(r.lists) > 0}
+	if r.pendingPrefix != "" {
+		blk.prefix = quote + r.pendingPrefix
+		blk.tight = len(r.blocks) > 0 && r.blocks[len(r.blocks)-1].inList
+		r.pendingPrefix = ""
+	}
+	r.blocks = append(r.blocks, blk)
+}
+
+// truncateBlocks keeps the first maxRunes runes of text, cutting at a word
+// boundary when there is one nearby and marking the cut with an ellipsis.
+func truncateBlocks(blocks []block, maxRunes int) []block {
+	remaining := maxRunes
+	for i, blk := range blocks {
+		n := utf8.RuneCountInString(blk.text)
+		if n <= remaining {
+			remaining -= n
+			continue
+		}
+		kept := blocks[:i:i]
+		if blk.text = Truncate(blk.text, remaining); blk.text != "…" {
+			return append(kept, blk)
+		}
+		// Nothing of this block fits, mark the end of the previous one instead
+		if len(kept) > 0 {
+			kept[i-1].text += "…"
+		}
+		return kept
+	}
+	return blocks
+}
+
+// Truncate shortens s to at most maxRunes runes plus an ellipsis, never
+// splitting a multi-byte character and preferring to cut between words.
+func Truncate(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	cut := 0
+	for i := range s {
+		if maxRunes == 0 {
+			cut = i
+			break
+		}
+		maxRunes--
+	}
+	head := s[:cut]
+	if space := strings.LastIndexAny(head, " \n"); space > len(head)/2 {
+		head = head[:space]
+	}
+	return strings.TrimRight(head, " \n") + "…"
+}
+
+func formatBlock(blk block, opts Options) string {
+	// Continuation lines of a list item line up with its text
+	hanging := blk.indent + strings.Repeat(" ", max(runeLen(blk.prefix)-runeLen(blk.indent), 0))
+	prefix := blk.prefix
+
+	var lines []string
+	for _, line := range strings.Split(blk.text, "\n") {
+		if blk.pre {
+			lines = append(lines, opts.Indent+prefix+line)
+			prefix = hanging
+			continue
+		}
+		for _, wrapped := range wrap(line, opts.Width-runeLen(opts.Indent)-runeLen(prefix)) {
+			lines = append(lines, opts.Indent+prefix+wrapped)
+			prefix = hanging
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap splits text into lines of at most width runes, breaking between
+// words. Words longer than width get a line of their own.
+func wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if width <= 0 {
+		return []string{strings.Join(words, " ")}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if runeLen(line)+1+runeLen(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\n\r\f", rune(s[0]))
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\n\r\f", rune(s[len(s)-1]))
+}
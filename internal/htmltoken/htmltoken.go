@@ -0,0 +1,100 @@
+// Package htmltoken splits HTML into tags, text and comments. It wraps the
+// golang.org/x/net/html tokenizer and keeps each token's source text, so
+// callers can rewrite a few tags and pass everything else through unchanged.
+package htmltoken
+
+import (
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+type TokenType int
+
+const (
+	TextToken TokenType = iota
+	StartTagToken
+	EndTagToken
+	SelfClosingTagToken
+	CommentToken
+	DoctypeToken
+)
+
+type Attribute struct {
+	Key string
+	Val string
+}
+
+type Token struct {
+	Type TokenType
+	// Data is the lower-cased tag name for tags, or the unescaped text for
+	// text and comment tokens
+	Data string
+	Attr []Attribute
+	// Raw is the token exactly as it appeared in the source
+	Raw string
+}
+
+// AttrVal returns the value of the named attribute.
+func (t Token) AttrVal(key string) (string, bool) {
+	for _, attr := range t.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// String renders the token as HTML. Tags are rebuilt from Data and Attr, so
+// changes to the attributes show up; everything else is returned as Raw.
+func (t Token) String() string {
+	var b strings.Builder
+	switch t.Type {
+	case StartTagToken, SelfClosingTagToken:
+		b.WriteString("<" + t.Data)
+		for _, attr := range t.Attr {
+			b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+		}
+		if t.Type == SelfClosingTagToken {
+			b.WriteString(" /")
+		}
+		b.WriteString(">")
+	case EndTagToken:
+		b.WriteString("</" + t.Data + ">")
+	default:
+		return t.Raw
+	}
+	return b.String()
+}
+
+// Tokenize splits src into tokens. Malformed or truncated markup never
+// fails: it comes back as text, or is dropped when the input ends inside a
+// tag.
+func Tokenize(src string) []Token {
+	var tokens []Token
+	z := nethtml.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			return tokens
+		}
+		// Copy Raw first, Token unescapes in place in the same buffer
+		raw := string(z.Raw())
+		t := z.Token()
+		tok := Token{Type: tokenTypes[tt], Data: t.Data, Raw: raw}
+		for _, attr := range t.Attr {
+			tok.Attr = append(tok.Attr, Attribute{Key: attr.Key, Val: attr.Val})
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+var tokenTypes = map[nethtml.TokenType]TokenType{
+	nethtml.TextToken:           TextToken,
+	nethtml.StartTagToken:       StartTagToken,
+	nethtml.EndTagToken:         EndTagToken,
+	nethtml.SelfClosingTagToken: SelfClosingTagToken,
+	nethtml.CommentToken:        CommentToken,
+	nethtml.DoctypeToken:        DoctypeToken,
+}
@@ -0,0 +1,121 @@
+package htmltoken
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize(`<p class=intro>Fish &amp; chips<br/><a HREF="/menu?a=1&amp;b=2">Menu</a></p><script>if (a < b) {}</script><!-- note -->`)
+	want := []Token{
+		{Type: StartTagToken, Data: "p", Attr: []Attribute{{Key: "class", Val: "intro"}}},
+		{Type: TextToken, Data: "Fish & chips"},
+		{Type: SelfClosingTagToken, Data: "br"},
+		{Type: StartTagToken, Data: "a", Attr: []Attribute{{Key: "href", Val: "/menu?a=1&b=2"}}},
+		{Type: TextToken, Data: "Menu"},
+		{Type: EndTagToken, Data: "a"},
+		{Type: EndTagToken, Data: "p"},
+		{Type: StartTagToken, Data: "script"},
+		{Type: TextToken, Data: "if (a < b) {}"},
+		{Type: EndTagToken, Data: "script"},
+		{Type: CommentToken, Data: " note "},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %+v", len(tokens), len(want), tokens)
+	}
+	for i, tok := range tokens {
+		if tok.Type != want[i].Type || tok.Data != want[i].Data || !equalAttrs(tok.Attr, want[i].Attr) {
+			t.Errorf("token %d = %+v, want %+v", i, tok, want[i])
+		}
+	}
+}
+
+func TestTokenizeKeepsRaw(t *testing.T) {
+	src := `<P Class="x">a &lt; b</P><img src='y.png' alt=z>`
+	var b strings.Builder
+	for _, tok := range Tokenize(src) {
+		b.WriteString(tok.Raw)
+	}
+	if b.String() != src {
+		t.Errorf("raw tokens = %q, want %q", b.String(), src)
+	}
+}
+
+func TestTokenizeMalformed(t *testing.T) {
+	inputs := []string{
+		"Hello <!",
+		"Hello <?",
+		"Hello <",
+		"Hello </",
+		"<!--",
+		"<!-- unterminated",
+		"<!DOCTYPE",
+		"<?xml version=\"1.0\"",
+		`<a href="unterminated`,
+		"<a href=",
+		"<p <p <p>",
+		"</>",
+		"<3 and a < b",
+		"<script>never closed",
+		"<<<>>>",
+	}
+	for _, src := range inputs {
+		t.Run(src, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Tokenize(%q) panicked: %v", src, r)
+				}
+			}()
+			for _, tok := range Tokenize(src) {
+				_ = tok.String()
+			}
+		})
+	}
+}
+
+func TestTokenizeTextAfterBrokenMarkup(t *testing.T) {
+	tokens := Tokenize("1 <3 you")
+	var text strings.Builder
+	for _, tok := range tokens {
+		if tok.Type != TextToken {
+			t.Fatalf("unexpected token %+v", tok)
+		}
+		text.WriteString(tok.Data)
+	}
+	if text.String() != "1 <3 you" {
+		t.Errorf("text = %q, want %q", text.String(), "1 <3 you")
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	tok := Tokenize(`<a href="/x" title='say "hi"'>`)[0]
+	tok.Attr[0].Val = "https://example.com/x?a=1&b=2"
+	want := `<a href="https://example.com/x?a=1&amp;b=2" title="say &#34;hi&#34;">`
+	if got := tok.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func equalAttrs(a, b []Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzTokenize(f *testing.F) {
+	f.Add(`<p>Hello <a href="/x">world</a></p>`)
+	f.Add("Hello <!")
+	f.Add("<?xml")
+	f.Add("<script><!--")
+	f.Fuzz(func(t *testing.T, src string) {
+		for _, tok := range Tokenize(src) {
+			_ = tok.String()
+		}
+	})
+}
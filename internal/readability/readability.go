@@ -0,0 +1,361 @@
+// Package readability extracts the main article from a web page, in the
+// spirit of Mozilla's Readability: paragraphs are scored by how much prose
+// they hold, the scores flow up to their containers, and the best container
+// plus its related siblings is returned as cleaned-up HTML.
+package readability
+
+import (
+	"errors"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/htmltoken"
+)
+
+// ErrNoArticle is returned when a page has no block of text that looks like
+// an article.
+var ErrNoArticle = errors.New("no article found")
+
+// Articles shorter than this are more likely a teaser or an error page
+const minArticleRunes = 250
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|blog|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|banner|combx|comment|com-|contact|foot|footnote|gdpr|hidden|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// Elements dropped with everything inside them
+var removedElements = map[string]bool{
+	"aside": true, "button": true, "embed": true, "footer": true,
+	"form": true, "head": true, "iframe": true, "input": true,
+	"link": true, "meta": true, "nav": true, "noscript": true,
+	"object": true, "script": true, "select": true, "style": true,
+	"svg": true, "template": true, "textarea": true,
+}
+
+// Elements that never have children or an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Elements whose presence makes a div a container rather than a paragraph
+var blockElements = map[string]bool{
+	"article": true, "blockquote": true, "div": true, "dl": true,
+	"figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "ul": true,
+}
+
+// Tags kept in the extracted HTML; others are replaced by their content
+var keptElements = map[string]bool{
+	"a": true, "b": true, "blockquote": true, "br": true, "code": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "em": true,
+	"figcaption": true, "figure": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "hr": true, "i": true, "img": true,
+	"li": true, "ol": true, "p": true, "pre": true, "section": true,
+	"strong": true, "table": true, "tbody": true, "td": true, "th": true,
+	"thead": true, "tr": true, "ul": true,
+}
+
+// Attributes kept in the extracted HTML, per tag
+var keptAttributes = map[string][]string{
+	"a":   {"href"},
+	"img": {"src", "alt"},
+}
+
+type node struct {
+	tag      string // Empty for text nodes
+	attrs    []htmltoken.Attribute
+	text     string
+	parent   *node
+	children []*node
+}
+
+// Extract returns the main article of an HTML page as simplified HTML.
+// URLs are left as they appear in the page.
+func Extract(page string) (string, error) {
+	root := parse(page)
+	removeClutter(root)
+
+	scores := scoreParagraphs(root)
+	var top *node
+	topScore := 0.0
+	// Walk in document order so ties go to the earlier candidate
+	walk(root, func(n *node) {
+		score, ok := scores[n]
+		if !ok {
+			return
+		}
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	})
+	if top == nil {
+		return "", ErrNoArticle
+	}
+
+	article := []*node{top}
+	if top.parent != nil {
+		article = relatedSiblings(top, topScore, scores)
+	}
+
+	text := 0
+	var b strings.Builder
+	for _, n := range article {
+		text += utf8.RuneCountInString(strings.TrimSpace(innerText(n)))
+		render(&b, n)
+	}
+	if text < minArticleRunes {
+		return "", ErrNoArticle
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// parse builds a tree from the page, closing elements the way browsers do
+// for the common cases of unclosed <p> and <li>.
+func parse(page string) *node {
+	root := &node{tag: "#root"}
+	cur := root
+	for _, tok := range htmltoken.Tokenize(page) {
+		switch tok.Type {
+		case htmltoken.TextToken:
+			cur.children = append(cur.children, &node{text: tok.Data, parent: cur})
+		case htmltoken.StartTagToken, htmltoken.SelfClosingTagToken:
+			if cur.tag == "p" && (blockElements[tok.Data] || tok.Data == "li") {
+				cur = cur.parent
+			}
+			if tok.Data == "li" {
+				for n := cur; n != root && n.tag != "ul" && n.tag != "ol"; n = n.parent {
+					if n.tag == "li" {
+						cur = n.parent
+						break
+					}
+				}
+			}
+			n := &node{tag: tok.Data, attrs: tok.Attr, parent: cur}
+			cur.children = append(cur.children, n)
+			if tok.Type == htmltoken.StartTagToken && !voidElements[tok.Data] {
+				cur = n
+			}
+		case htmltoken.EndTagToken:
+			for n := cur; n != root; n = n.parent {
+				if n.tag == tok.Data {
+					cur = n.parent
+					break
+				}
+			}
+		}
+	}
+	return root
+}
+
+// removeClutter drops scripts, navigation and blocks whose class or id
+// marks them as comments, sidebars, share buttons and the like.
+func removeClutter(n *node) {
+	kept := n.children[:0]
+	for _, child := range n.children {
+		if child.tag != "" {
+			if removedElements[child.tag] {
+				continue
+			}
+			if hint := classAndID(child); child.tag != "body" && child.tag != "article" &&
+				unlikelyCandidates.MatchString(hint) && !maybeCandidate.MatchString(hint) {
+				continue
+			}
+			removeClutter(child)
+		}
+		kept = append(kept, child)
+	}
+	n.children = kept
+}
+
+// scoreParagraphs gives every container a score from the paragraphs in it.
+func scoreParagraphs(root *node) map[*node]float64 {
+	scores := map[*node]float64{}
+	walk(root, func(n *node) {
+		if !isParagraph(n) {
+			return
+		}
+		text := strings.TrimSpace(innerText(n))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，"))
+		score += math.Min(float64(length/100), 3)
+
+		level := 0
+		for ancestor := n.parent; ancestor != nil && ancestor.tag != "#root" && level < 3; ancestor = ancestor.parent {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+			}
+			switch level {
+			case 0:
+				scores[ancestor] += score
+			case 1:
+				scores[ancestor] += score / 2
+			default:
+				scores[ancestor] += score / float64(level*3)
+			}
+			level++
+		}
+	})
+	return scores
+}
+
+// isParagraph reports whether n holds prose of its own: a p, pre or td, or
+// a div used like a paragraph because it contains no other blocks.
+func isParagraph(n *node) bool {
+	switch n.tag {
+	case "p", "pre", "td":
+		return true
+	case "div":
+		for _, child := range n.children {
+			if blockElements[child.tag] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func initialScore(n *node) float64 {
+	score := 0.0
+	switch n.tag {
+	case "div", "article":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	hint := classAndID(n)
+	if negativeWeight.MatchString(hint) {
+		score -= 25
+	}
+	if positiveWeight.MatchString(hint) {
+		score += 25
+	}
+	return score
+}
+
+// relatedSiblings returns top together with the siblings that look like
+// part of the same article, in document order.
+func relatedSiblings(top *node, topScore float64, scores map[*node]float64) []*node {
+	threshold := math.Max(10, topScore*0.2)
+	var article []*node
+	for _, sibling := range top.parent.children {
+		if sibling.tag == "" {
+			continue
+		}
+		include := sibling == top
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			include = true
+		}
+		if sibling.tag == "p" {
+			text := strings.TrimSpace(innerText(sibling))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.HasSuffix(text, ".")) {
+				include = true
+			}
+		}
+		if include {
+			article = append(article, sibling)
+		}
+	}
+	return article
+}
+
+// linkDensity is the share of a node's text that sits inside links.
+func linkDensity(n *node) float64 {
+	total := utf8.RuneCountInString(strings.TrimSpace(innerText(n)))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walk(n, func(child *node) {
+		if child.tag == "a" {
+			linked += utf8.RuneCountInString(strings.TrimSpace(innerText(child)))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+func classAndID(n *node) string {
+	class, _ := attr(n, "class")
+	id, _ := attr(n, "id")
+	return class + " " + id
+}
+
+func attr(n *node, key string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func innerText(n *node) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(innerText(child))
+		if blockElements[child.tag] {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+func walk(n *node, visit func(*node)) {
+	visit(n)
+	for _, child := range n.children {
+		walk(child, visit)
+	}
+}
+
+// render writes n as HTML, keeping only simple formatting tags and the
+// attributes links and images need.
+func render(b *strings.Builder, n *node) {
+	if n.tag == "" {
+		b.WriteString(html.EscapeString(n.text))
+		return
+	}
+	kept := keptElements[n.tag]
+	if kept {
+		b.WriteString("<" + n.tag)
+		for _, key := range keptAttributes[n.tag] {
+			if val, ok := attr(n, key); ok {
+				b.WriteString(" " + key + `="` + html.EscapeString(val) + `"`)
+			}
+		}
+		b.WriteString(">")
+		if voidElements[n.tag] {
+			return
+		}
+	}
+	for _, child := range n.children {
+		render(b, child)
+	}
+	if kept {
+		b.WriteString("</" + n.tag + ">")
+	}
+}
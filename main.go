@@ -12,8 +12,11 @@ import (
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db     *database.Queries
+	sqlDB  *sql.DB // For running queries in a transaction
+	cfg    *config.Config
+	client *feedClient
+	urls   *urlCanonicalizer
 }
 
 func parseArgs() (cmdName string, cmdArgs []string, err error) {
@@ -51,6 +54,12 @@ func main() {
 		log.Fatalf("error reading config: %v", err)
 	}
 
+	// Build the HTTP client used for fetching feeds
+	client, err := newFeedClient(cfg.Fetch)
+	if err != nil {
+		log.Fatalf("error reading config: %v", err)
+	}
+
 	// Open database connection
 	db, err := sql.Open("postgres", cfg.DBURL)
 	if err != nil {
@@ -62,8 +71,11 @@ func main() {
 
 	// Create a state object, which contains the config from Read
 	programState := &state{
-		cfg: &cfg,
-		db:  dbQueries,
+		cfg:    &cfg,
+		db:     dbQueries,
+		sqlDB:  db,
+		client: client,
+		urls:   newURLCanonicalizer(cfg.URLs),
 	}
 
 	// Create commands struct and initializes empty map
@@ -83,6 +95,9 @@ func main() {
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollowFeed))
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("read", middlewareLoggedIn(handlerRead))
+	cmds.register("enablefeed", middlewareLoggedIn(handlerEnableFeed))
+	cmds.register("editfeed", middlewareLoggedIn(handlerEditFeed))
 
 	// Runs command if command not found return error with code 1
 	err = cmds.run(programState, command{Name: cmdName, Args: cmdArgs})
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a host's robots.txt is trusted before it is fetched again
+const robotsCacheTTL = 24 * time.Hour
+
+// How soon an unreachable robots.txt is tried again
+const robotsRetryTTL = 30 * time.Minute
+
+// robotsRules holds the Allow/Disallow rules that apply to gator on a host.
+type robotsRules struct {
+	allow    []string
+	disallow []string
+}
+
+// allowed applies the longest matching rule to path, with Allow winning
+// ties, as described in RFC 9309.
+func (r *robotsRules) allowed(path string) bool {
+	best := -1
+	allowed := true
+	for _, rule := range r.disallow {
+		if len(rule) > best && robotsMatch(rule, path) {
+			best = len(rule)
+			allowed = false
+		}
+	}
+	for _, rule := range r.allow {
+		if len(rule) >= best && robotsMatch(rule, path) {
+			best = len(rule)
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a robots.txt path pattern, which may use * wildcards
+// and a trailing $ anchor.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(pattern, "$")), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	matched, err := regexp.MatchString(expr, path)
+	return err == nil && matched
+}
+
+// parseRobots extracts the rules for userAgent from a robots.txt body,
+// falling back to the "*" group when there is no specific one.
+func parseRobots(body io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i != -1 {
+		token = token[:i]
+	}
+
+	var specific, wildcard *robotsRules
+	var current []*robotsRules // Groups the current rule lines belong to
+	inAgents := false
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = nil
+				inAgents = true
+			}
+			agent := strings.ToLower(value)
+			switch {
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			case agent == token:
+				if specific == nil {
+					specific = &robotsRules{}
+				}
+				current = append(current, specific)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue // An empty Disallow allows everything
+			}
+			for _, group := range current {
+				if key == "allow" {
+					group.allow = append(group.allow, value)
+				} else {
+					group.disallow = append(group.disallow, value)
+				}
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	if specific != nil {
+		return specific
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &robotsRules{}
+}
+
+type robotsEntry struct {
+	rules     *robotsRules
+	expiresAt time.Time
+}
+
+// robotsCache fetches and remembers robots.txt per scheme and host.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]robotsEntry)}
+}
+
+// allowed reports whether robots.txt on the target's host lets userAgent
+// fetch it. A missing robots.txt allows everything. One that can't be
+// reached (a network error or 5xx) disallows everything, as RFC 9309 asks,
+// unless an earlier copy was read; either way it is retried sooner.
+func (c *robotsCache) allowed(ctx context.Context, client *http.Client, target *url.URL, userAgent string) bool {
+	origin := target.Scheme + "://" + target.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[origin]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		rules, reachable := fetchRobots(ctx, client, origin, userAgent)
+		switch {
+		case reachable:
+			entry = robotsEntry{rules: rules, expiresAt: time.Now().Add(robotsCacheTTL)}
+		case ok:
+			entry.expiresAt = time.Now().Add(robotsRetryTTL)
+		default:
+			entry = robotsEntry{rules: &robotsRules{disallow: []string{"/"}}, expiresAt: time.Now().Add(robotsRetryTTL)}
+		}
+		c.mu.Lock()
+		c.entries[origin] = entry
+		c.mu.Unlock()
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	return entry.rules.allowed(path)
+}
+
+// fetchRobots reads the robots.txt of origin. It reports false when the
+// server couldn't be reached or failed to answer.
+func fetchRobots(ctx context.Context, client *http.Client, origin, userAgent string) (*robotsRules, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, false
+	case resp.StatusCode != http.StatusOK:
+		return &robotsRules{}, true // No robots.txt, or none we may read
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512<<10), userAgent), true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	body := `
+# Comments and blank lines are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/public.xml
+
+User-agent: gator
+User-agent: otherbot
+Disallow: /feeds/*.json$
+Disallow: /drafts
+Allow: /drafts/published
+
+User-agent: evilbot
+Disallow: /
+`
+	tests := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		// gator has its own group, so the * rules don't apply
+		{"gator/1.0", "/private/feed.xml", true},
+		{"gator/1.0", "/feeds/all.json", false},
+		{"gator/1.0", "/feeds/all.json?page=2", true},
+		{"gator/1.0", "/feeds/all.xml", true},
+		{"gator/1.0", "/drafts/new", false},
+		{"gator/1.0", "/drafts/published/post", true},
+		{"Gator", "/drafts", false},
+		// Everyone else falls back to *
+		{"curl/8.0", "/private/feed.xml", false},
+		{"curl/8.0", "/private/public.xml", true},
+		{"curl/8.0", "/drafts", true},
+	}
+	for _, tt := range tests {
+		rules := parseRobots(strings.NewReader(body), tt.userAgent)
+		if allowed := rules.allowed(tt.path); allowed != tt.allowed {
+			t.Errorf("%s allowed(%s) = %v, want %v", tt.userAgent, tt.path, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestParseRobotsEmpty(t *testing.T) {
+	for _, body := range []string{"", "User-agent: *\nDisallow:\n", "not a robots file"} {
+		if !parseRobots(strings.NewReader(body), "gator").allowed("/feed.xml") {
+			t.Errorf("robots.txt %q disallows /feed.xml", body)
+		}
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/", "/anything", true},
+		{"/feed", "/feed.xml", true},
+		{"/feed", "/blog/feed", false},
+		{"/*.xml", "/blog/feed.xml", true},
+		{"/*.xml$", "/blog/feed.xml?x=1", false},
+		{"/feed.xml$", "/feed.xml", true},
+		{"/a.b", "/axb", false},
+	}
+	for _, tt := range tests {
+		if match := robotsMatch(tt.pattern, tt.path); match != tt.match {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, match, tt.match)
+		}
+	}
+}
+
+func TestRobotsCacheStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		allowed bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusForbidden, true},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		target, _ := url.Parse(server.URL + "/feed.xml")
+		if allowed := newRobotsCache().allowed(context.Background(), server.Client(), target, "gator"); allowed != tt.allowed {
+			t.Errorf("robots.txt status %d: allowed = %v, want %v", tt.status, allowed, tt.allowed)
+		}
+		server.Close()
+	}
+}
+
+func TestRobotsCacheUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target, _ := url.Parse(server.URL + "/feed.xml")
+	server.Close()
+
+	if newRobotsCache().allowed(context.Background(), server.Client(), target, "gator") {
+		t.Error("unreachable robots.txt allowed the fetch")
+	}
+}
+
+func TestRobotsCacheKeepsLastCopy(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		}
+	}))
+	defer server.Close()
+
+	cache := newRobotsCache()
+	public, _ := url.Parse(server.URL + "/feed.xml")
+	private, _ := url.Parse(server.URL + "/private/feed.xml")
+	if !cache.allowed(context.Background(), server.Client(), public, "gator") {
+		t.Fatal("public feed disallowed")
+	}
+
+	// Once the copy expires and the server fails, the last rules still apply
+	status = http.StatusInternalServerError
+	origin := public.Scheme + "://" + public.Host
+	entry := cache.entries[origin]
+	entry.expiresAt = time.Now().Add(-time.Minute)
+	cache.entries[origin] = entry
+
+	if !cache.allowed(context.Background(), server.Client(), public, "gator") {
+		t.Error("public feed disallowed after robots.txt became unreachable")
+	}
+	if cache.allowed(context.Background(), server.Client(), private, "gator") {
+		t.Error("private feed allowed after robots.txt became unreachable")
+	}
+	if retry := time.Until(cache.entries[origin].expiresAt); retry > robotsRetryTTL {
+		t.Errorf("unreachable robots.txt cached for %s, want at most %s", retry, robotsRetryTTL)
+	}
+}
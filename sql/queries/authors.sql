@@ -0,0 +1,18 @@
+-- name: AddPostAuthor :exec
+INSERT INTO post_authors (post_id, author_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetAuthorsForPost :many
+SELECT authors.*
+FROM authors
+JOIN post_authors ON post_authors.author_id = authors.id
+WHERE post_authors.post_id = $1
+ORDER BY authors.name;
+
+-- name: UpsertAuthor :one
+-- The no-op update makes RETURNING yield the existing row on conflict
+INSERT INTO authors (id, created_at, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
@@ -0,0 +1,18 @@
+-- name: AddPostCategory :exec
+INSERT INTO post_categories (post_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetCategoriesForPost :many
+SELECT categories.*
+FROM categories
+JOIN post_categories ON post_categories.category_id = categories.id
+WHERE post_categories.post_id = $1
+ORDER BY categories.name;
+
+-- name: UpsertCategory :one
+-- The no-op update makes RETURNING yield the existing row on conflict
+INSERT INTO categories (id, created_at, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
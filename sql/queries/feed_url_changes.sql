@@ -0,0 +1,8 @@
+-- name: CreateFeedURLChange :exec
+INSERT INTO feed_url_changes (id, created_at, feed_id, user_id, old_url, new_url, merged)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetFeedURLChanges :many
+SELECT * FROM feed_url_changes
+WHERE feed_id = $1
+ORDER BY created_at DESC;
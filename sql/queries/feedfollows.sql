@@ -25,4 +25,13 @@ WHERE feed_follows.user_id = $1;
 -- name: DeleteFeedFollowByUserAndFeedURL :exec
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1 -- Qualify user_id with table name
-AND feed_id = (SELECT id FROM feeds WHERE url = $2);
\ No newline at end of file
+AND feed_id = (SELECT id FROM feeds WHERE url = $2);
+
+-- name: MoveFeedFollows :exec
+-- Copies the follows of one feed onto another, skipping users who already
+-- follow the target feed.
+INSERT INTO feed_follows (user_id, feed_id)
+SELECT feed_follows.user_id, sqlc.arg(to_feed_id)::uuid
+FROM feed_follows
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
\ No newline at end of file
@@ -18,17 +18,106 @@ WHERE name = $1 LIMIT 1;
 SELECT * FROM feeds;
 
 -- name: GetFeedByURL :one
+-- Takes every spelling the URL may be stored under, see urlCanonicalizer
 SELECT * FROM feeds
-WHERE url = $1 LIMIT 1;
+WHERE url = ANY(sqlc.arg(urls)::TEXT[])
+ORDER BY created_at
+LIMIT 1;
 
--- name: MarkFeedFetched :exec
+-- name: GetNextFeedToFetch :one
+-- Claims the stalest due feed by stamping last_fetched_at in the same
+-- statement. SKIP LOCKED lets concurrent aggregators pass over a row another
+-- one is claiming instead of both picking it, and pushing next_fetch_at out
+-- leases the feed to this fetch until it schedules the real next fetch. A
+-- fetch that dies without doing so frees the feed when the lease runs out.
 UPDATE feeds
 SET last_fetched_at = NOW(),
+    next_fetch_at = NOW() + INTERVAL '15 minutes',
+    updated_at = NOW()
+WHERE id = (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2,
+    last_modified = $3,
     updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT *
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+
+-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_success_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING consecutive_failures;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    consecutive_failures = 0,
+    next_fetch_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedPollingHints :exec
+UPDATE feeds
+SET min_poll_minutes = $2,
+    skip_hours = $3,
+    skip_days = $4,
+    updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: SetFeedCredentials :exec
+UPDATE feeds
+SET credentials = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedFetchFullText :exec
+UPDATE feeds
+SET fetch_full_text = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
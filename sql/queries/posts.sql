@@ -1,4 +1,95 @@
--- name: CreatePost :one
+-- name: AdoptPostByURL :one
+-- Posts stored before GUIDs were tracked, and items without a GUID, are
+-- keyed by their link. When such an item turns up under a real GUID or
+-- another spelling of its link, the post takes that key over.
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE id = (
+    SELECT keyed.id FROM posts AS keyed
+    WHERE keyed.feed_id = sqlc.arg(feed_id)
+    AND keyed.guid = ANY(sqlc.arg(urls)::TEXT[])
+    ORDER BY keyed.created_at
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: FillPostContent :exec
+-- Stores the content of a post saved before content was kept, without
+-- counting it as a revision.
+UPDATE posts
+SET content = $2
+WHERE id = $1 AND content IS NULL;
+
+-- name: GetPostByGUID :one
+SELECT * FROM posts
+WHERE feed_id = $1 AND guid = $2;
+
+-- name: GetPostForUserByURL :one
+SELECT posts.*
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND posts.url = ANY(sqlc.arg(urls)::TEXT[])
+ORDER BY posts.published_at DESC
+LIMIT 1;
+
+-- name: GetPostsForUser :many
+-- Author and category filters are optional and match names case-insensitively
+SELECT posts.*
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(author)::TEXT IS NULL OR EXISTS (
+    SELECT 1 FROM post_authors
+    JOIN authors ON authors.id = post_authors.author_id
+    WHERE post_authors.post_id = posts.id AND lower(authors.name) = lower(sqlc.narg(author))
+))
+AND (sqlc.narg(category)::TEXT IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    JOIN categories ON categories.id = post_categories.category_id
+    WHERE post_categories.post_id = posts.id AND lower(categories.name) = lower(sqlc.narg(category))
+))
+ORDER BY posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetPostsMissingFullText :many
+SELECT * FROM posts
+WHERE feed_id = $1 AND full_text IS NULL AND full_text_attempts < $2
+ORDER BY published_at DESC
+LIMIT $3;
+
+-- name: GetRecentPostDatesForFeed :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: MovePostsToFeed :exec
+-- Posts the target feed already has stay behind and go away with the old feed
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = sqlc.arg(to_feed_id) AND existing.guid = posts.guid
+);
+
+-- name: RecordFullTextAttempt :exec
+UPDATE posts
+SET full_text_attempts = full_text_attempts + 1
+WHERE id = $1;
+
+-- name: SetPostFullText :exec
+UPDATE posts
+SET full_text = $2
+WHERE id = $1;
+
+-- name: UpsertPost :one
+-- Inserts a new post or overwrites the stored version of an item that
+-- changed. published_at keeps its original value since it is made up for
+-- items without a date, and the extracted full text is dropped so it is
+-- fetched again.
 INSERT INTO posts (
     id,
     created_at,
@@ -7,14 +98,17 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    feed_id,
+    content,
+    guid
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    updated_at = EXCLUDED.updated_at,
+    revised_at = EXCLUDED.updated_at,
+    full_text = NULL,
+    full_text_attempts = 0
 RETURNING *;
-
--- name: GetPostsForUser :many
-SELECT posts.*
-FROM posts
-JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
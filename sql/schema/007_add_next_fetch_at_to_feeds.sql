@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN next_fetch_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN next_fetch_at;
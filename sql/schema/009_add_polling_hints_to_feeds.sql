@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN min_poll_minutes INTEGER NULL,
+ADD COLUMN skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+ADD COLUMN skip_days TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN min_poll_minutes,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;
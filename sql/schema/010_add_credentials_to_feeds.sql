@@ -0,0 +1,8 @@
+-- +goose Up
+-- Encrypted JSON blob with the authentication used to fetch the feed
+ALTER TABLE feeds
+ADD COLUMN credentials BYTEA NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN credentials;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Full article body (content:encoded, Atom content), description keeps the teaser
+ALTER TABLE posts
+ADD COLUMN content TEXT NULL;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE authors (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE post_authors (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, author_id)
+);
+
+CREATE TABLE categories (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, category_id)
+);
+
+-- browse filters by name without regard to case
+CREATE INDEX authors_lower_name_idx ON authors (lower(name));
+CREATE INDEX categories_lower_name_idx ON categories (lower(name));
+
+-- +goose Down
+DROP TABLE post_categories;
+DROP TABLE categories;
+DROP TABLE post_authors;
+DROP TABLE authors;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Posts are identified by the item GUID (or Atom id) within their feed. Items
+-- without one are keyed by their link. Existing posts were identified by URL,
+-- so that becomes their GUID until their item is seen again.
+ALTER TABLE posts
+ADD COLUMN guid TEXT;
+
+UPDATE posts SET guid = url;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL,
+DROP CONSTRAINT posts_url_key,
+ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+CREATE INDEX posts_url_idx ON posts (url);
+
+-- +goose Down
+DROP INDEX posts_url_idx;
+
+-- Keep the newest post for each URL so the old constraint can come back
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at DESC) AS n
+        FROM posts
+    ) ranked
+    WHERE n > 1
+);
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_guid_key,
+ADD CONSTRAINT posts_url_key UNIQUE (url),
+DROP COLUMN guid;
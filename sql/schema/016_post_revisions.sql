@@ -0,0 +1,20 @@
+-- +goose Up
+-- Previous versions of posts whose title, link or text changed in the feed
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    content TEXT
+);
+
+ALTER TABLE posts
+ADD COLUMN revised_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN revised_at;
+
+DROP TABLE post_revisions;
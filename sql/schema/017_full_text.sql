@@ -0,0 +1,14 @@
+-- +goose Up
+-- Opt-in per feed: download each new post's page and extract the article
+ALTER TABLE feeds
+ADD COLUMN fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts
+ADD COLUMN full_text TEXT NULL;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN full_text;
+
+ALTER TABLE feeds
+DROP COLUMN fetch_full_text;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Pages that fail to download are retried a few times, then given up on
+ALTER TABLE posts
+ADD COLUMN full_text_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN full_text_attempts;
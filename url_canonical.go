@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/config"
+)
+
+// Query parameters added by newsletters, ads and analytics that never change
+// what a page shows
+var defaultTrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"yclid",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"_hsenc",
+	"_hsmi",
+}
+
+// urlCanonicalizer rewrites feed and post URLs into one spelling so the
+// same feed or article isn't stored twice.
+type urlCanonicalizer struct {
+	trackingParams []string // Exact names, or prefixes ending in "*"
+}
+
+func newURLCanonicalizer(cfg config.URLConfig) *urlCanonicalizer {
+	c := &urlCanonicalizer{trackingParams: defaultTrackingParams}
+	if len(cfg.TrackingParams) > 0 {
+		c.trackingParams = cfg.TrackingParams
+	}
+	if cfg.KeepTrackingParams {
+		c.trackingParams = nil
+	}
+	return c
+}
+
+// canonical lowercases the scheme and host, drops default ports, fragments
+// and tracking parameters, and gives host-only URLs a "/" path. Scheme and
+// trailing slashes are kept, since servers may not treat those spellings
+// the same; variants covers them when looking URLs up. Anything that isn't
+// an absolute http(s) URL is returned as is.
+func (c *urlCanonicalizer) canonical(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return rawURL
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = c.stripTrackingParams(u.RawQuery)
+	u.ForceQuery = false
+	return u.String()
+}
+
+// stripTrackingParams removes tracking parameters from a raw query string,
+// leaving the order and encoding of the others untouched.
+func (c *urlCanonicalizer) stripTrackingParams(rawQuery string) string {
+	if rawQuery == "" || len(c.trackingParams) == 0 {
+		return rawQuery
+	}
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !c.isTrackingParam(strings.ToLower(name)) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func (c *urlCanonicalizer) isTrackingParam(name string) bool {
+	for _, pattern := range c.trackingParams {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// variants returns the spellings a URL may have been stored under: the
+// canonical URL with http and https, with and without a trailing slash,
+// plus the URL exactly as given.
+func (c *urlCanonicalizer) variants(rawURL string) []string {
+	canonical := c.canonical(rawURL)
+	result := []string{canonical}
+	add := func(v string) {
+		if !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+
+	u, err := url.Parse(canonical)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		// Work on the string so the path keeps its original escaping
+		base, query, hasQuery := strings.Cut(strings.TrimPrefix(canonical, u.Scheme+":"), "?")
+		bases := []string{base}
+		if u.Path != "/" {
+			if trimmed, ok := strings.CutSuffix(base, "/"); ok {
+				bases = append(bases, trimmed)
+			} else {
+				bases = append(bases, base+"/")
+			}
+		}
+		for _, scheme := range []string{"https", "http"} {
+			for _, b := range bases {
+				v := scheme + ":" + b
+				if hasQuery {
+					v += "?" + query
+				}
+				add(v)
+			}
+		}
+	}
+	add(strings.TrimSpace(rawURL))
+	return result
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/config"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://Example.COM/Blog/Post", "https://example.com/Blog/Post"},
+		{"HTTPS://example.com/post", "https://example.com/post"},
+		{"http://example.com:80/post", "http://example.com/post"},
+		{"https://example.com:443/post", "https://example.com/post"},
+		{"https://example.com:8443/post", "https://example.com:8443/post"},
+		{"http://example.com:443/post", "http://example.com:443/post"},
+		{"https://example.com", "https://example.com/"},
+		{"https://example.com/post#comments", "https://example.com/post"},
+		{"https://example.com/post/", "https://example.com/post/"},
+		{"https://example.com/post?utm_source=rss&utm_medium=feed", "https://example.com/post"},
+		{"https://example.com/post?id=7&utm_campaign=x&fbclid=abc&page=2", "https://example.com/post?id=7&page=2"},
+		{"https://example.com/post?UTM_Source=rss&q=a%20b", "https://example.com/post?q=a%20b"},
+		{"https://example.com/post?", "https://example.com/post"},
+		{"https://example.com/a%2Fb", "https://example.com/a%2Fb"},
+		{"  https://example.com/post  ", "https://example.com/post"},
+		{"mailto:Someone@Example.com", "mailto:Someone@Example.com"},
+		{"FTP://Example.com/feed", "FTP://Example.com/feed"},
+		{"/relative/post", "/relative/post"},
+		{"", ""},
+	}
+	c := newURLCanonicalizer(config.URLConfig{})
+	for _, tt := range tests {
+		if got := c.canonical(tt.in); got != tt.want {
+			t.Errorf("canonical(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalTrackingConfig(t *testing.T) {
+	const in = "https://example.com/post?utm_source=rss&ref=home&ref_src=tw&id=1"
+	tests := []struct {
+		name string
+		cfg  config.URLConfig
+		want string
+	}{
+		{"defaults", config.URLConfig{}, "https://example.com/post?ref=home&ref_src=tw&id=1"},
+		{"custom list", config.URLConfig{TrackingParams: []string{"ref*"}}, "https://example.com/post?utm_source=rss&id=1"},
+		{"exact name", config.URLConfig{TrackingParams: []string{"ref"}}, "https://example.com/post?utm_source=rss&ref_src=tw&id=1"},
+		{"keep all", config.URLConfig{KeepTrackingParams: true}, in},
+	}
+	for _, tt := range tests {
+		if got := newURLCanonicalizer(tt.cfg).canonical(in); got != tt.want {
+			t.Errorf("%s: canonical = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"https://Example.com/post?utm_source=rss", []string{
+			"https://example.com/post",
+			"https://example.com/post/",
+			"http://example.com/post",
+			"http://example.com/post/",
+			"https://Example.com/post?utm_source=rss",
+		}},
+		{"http://example.com/post/?id=1", []string{
+			"http://example.com/post/?id=1",
+			"https://example.com/post/?id=1",
+			"https://example.com/post?id=1",
+			"http://example.com/post?id=1",
+		}},
+		{"https://example.com", []string{
+			"https://example.com/",
+			"http://example.com/",
+			"https://example.com",
+		}},
+		{"tag:example.com,2024:post-1", []string{"tag:example.com,2024:post-1"}},
+	}
+	c := newURLCanonicalizer(config.URLConfig{})
+	for _, tt := range tests {
+		got := c.variants(tt.in)
+		if got[0] != c.canonical(tt.in) {
+			t.Errorf("variants(%q)[0] = %q, want the canonical URL first", tt.in, got[0])
+		}
+		slices.Sort(got)
+		want := slices.Clone(tt.want)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("variants(%q) = %q, want %q", tt.in, got, want)
+		}
+	}
+}
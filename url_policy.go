@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Address ranges that the std library predicates below do not already cover
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This network"
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, can reach IPv4 private ranges
+}
+
+// urlPolicy decides which URLs gator may fetch. Only http(s) is allowed and
+// private, loopback and link-local destinations are rejected unless they
+// are on the allowlist.
+type urlPolicy struct {
+	allowedHosts    map[string]bool
+	allowedPrefixes []netip.Prefix
+}
+
+// newURLPolicy builds a policy from the allowlist in the config. Entries are
+// either host names or IP addresses / CIDR ranges.
+func newURLPolicy(allow []string) (*urlPolicy, error) {
+	policy := &urlPolicy{allowedHosts: make(map[string]bool)}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			policy.allowedPrefixes = append(policy.allowedPrefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			policy.allowedPrefixes = append(policy.allowedPrefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		if strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("invalid allowlist entry %q", entry)
+		}
+		policy.allowedHosts[entry] = true
+	}
+	return policy, nil
+}
+
+// checkScheme rejects anything that is not a plain web URL.
+func (p *urlPolicy) checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url scheme %q is not allowed, use http or https", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("url has no host")
+	}
+	return nil
+}
+
+// checkIP reports an error when ip is an internal address that is not
+// allowlisted.
+func (p *urlPolicy) checkIP(ip netip.Addr) error {
+	ip = ip.Unmap()
+	for _, prefix := range p.allowedPrefixes {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+
+	blocked := ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified()
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			blocked = true
+		}
+	}
+	if blocked {
+		return fmt.Errorf("destination %s is a private or internal address", ip)
+	}
+	return nil
+}
+
+// checkURL validates the scheme and resolves the host to make sure none of
+// its addresses are internal. It is used up front (addfeed, proxied
+// requests); direct connections are checked again when dialing.
+func (p *urlPolicy) checkURL(ctx context.Context, u *url.URL) error {
+	if err := p.checkScheme(u); err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Hostname())
+	if p.allowedHosts[host] {
+		return nil
+	}
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.checkIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if err := p.checkIP(addr); err != nil {
+			return fmt.Errorf("%s resolves to a blocked address: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// dialContext returns a dialer for http.Transport that checks the address
+// actually connected to, after DNS resolution, so DNS rebinding and
+// redirects can't reach internal hosts. Proxy hosts are always allowed,
+// since the operator configured them.
+func (p *urlPolicy) dialContext(proxyHosts []string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	open := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	checked := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			return p.checkIP(ip)
+		},
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(host)
+		if p.allowedHosts[host] || slices.Contains(proxyHosts, host) {
+			return open.DialContext(ctx, network, addr)
+		}
+		return checked.DialContext(ctx, network, addr)
+	}
+}
+
+// envProxyHosts returns the hosts of the proxies configured through the
+// standard proxy environment variables.
+func envProxyHosts() []string {
+	var hosts []string
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "://") {
+			value = "http://" + value
+		}
+		if proxyURL, err := url.Parse(value); err == nil && proxyURL.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(proxyURL.Hostname()))
+		}
+	}
+	return hosts
+}
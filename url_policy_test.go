@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Ernestlph/Blog_Aggregator/internal/config"
+)
+
+func TestCheckIP(t *testing.T) {
+	policy, err := newURLPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a00:1", true},
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"::ffff:8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		err := policy.checkIP(netip.MustParseAddr(tt.ip))
+		if blocked := err != nil; blocked != tt.blocked {
+			t.Errorf("checkIP(%s) blocked = %v, want %v (err: %v)", tt.ip, blocked, tt.blocked, err)
+		}
+	}
+}
+
+func TestCheckIPAllowlist(t *testing.T) {
+	policy, err := newURLPolicy([]string{"10.20.0.0/16", " 192.168.1.5 ", "fd00::/8", "Intranet.Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"10.20.3.4", false},
+		{"::ffff:10.20.0.1", false},
+		{"10.21.0.1", true},
+		{"192.168.1.5", false},
+		{"192.168.1.6", true},
+		{"fd12::1", false},
+		{"fe80::1", true},
+		{"127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		err := policy.checkIP(netip.MustParseAddr(tt.ip))
+		if blocked := err != nil; blocked != tt.blocked {
+			t.Errorf("checkIP(%s) blocked = %v, want %v (err: %v)", tt.ip, blocked, tt.blocked, err)
+		}
+	}
+	if !policy.allowedHosts["intranet.example.com"] {
+		t.Errorf("allowed hosts = %v, want intranet.example.com", policy.allowedHosts)
+	}
+}
+
+func TestNewURLPolicyInvalid(t *testing.T) {
+	for _, entry := range []string{"http://intranet", "10.0.0.0/33", "host:8080"} {
+		if _, err := newURLPolicy([]string{entry}); err == nil {
+			t.Errorf("newURLPolicy(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	policy, err := newURLPolicy([]string{"intranet.example.com", "10.20.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://93.184.216.34/feed.xml", true},
+		{"http://intranet.example.com/rss", true},
+		{"http://10.20.0.7/rss", true},
+		{"http://127.0.0.1/", false},
+		{"http://[::1]:8080/feed", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[::ffff:192.168.0.1]/", false},
+		{"http://10.21.0.7/rss", false},
+		{"file:///etc/passwd", false},
+		{"ftp://93.184.216.34/feed.xml", false},
+		{"gopher://intranet.example.com/", false},
+		{"http:///feed.xml", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = policy.checkURL(context.Background(), u)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("checkURL(%s) allowed = %v, want %v (err: %v)", tt.url, allowed, tt.allowed, err)
+		}
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		allow   []string
+		proxies []string
+		allowed bool
+	}{
+		{"loopback", nil, nil, false},
+		{"allowlisted address", []string{"127.0.0.1"}, nil, true},
+		{"allowlisted range", []string{"127.0.0.0/8"}, nil, true},
+		{"proxy host", nil, []string{"127.0.0.1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newURLPolicy(tt.allow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &http.Transport{DialContext: policy.dialContext(tt.proxies)}}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if allowed := err == nil; allowed != tt.allowed {
+				t.Errorf("allowed = %v, want %v (err: %v)", allowed, tt.allowed, err)
+			}
+		})
+	}
+}
+
+func TestFeedClientRedirectToPrivateAddress(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"http://169.254.169.254/latest/meta-data/", "private or internal address"},
+		{"http://10.0.0.1/admin", "private or internal address"},
+		{"http://[::1]/", "private or internal address"},
+		{"file:///etc/passwd", `url scheme "file" is not allowed`},
+	}
+	for _, tt := range tests {
+		target := tt.target
+		t.Run(target, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, target, http.StatusFound)
+			}))
+			defer server.Close()
+
+			// The test server itself lives on loopback
+			client, err := newFeedClient(config.FetchConfig{Allow: []string{"127.0.0.1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				t.Fatalf("redirect to %s was followed", target)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Do = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedClientThroughProxyChecksURL(t *testing.T) {
+	// Through a proxy the dialer only sees the proxy, so the target is
+	// checked before the request is sent; the proxy is never contacted
+	client, err := newFeedClient(config.FetchConfig{FetchSettings: config.FetchSettings{Proxy: "http://127.0.0.1:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "http://169.254.169.254/latest/meta-data/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(req)
+	if err == nil || !strings.Contains(err.Error(), "private or internal address") {
+		t.Errorf("Do through proxy = %v, want a blocked address error", err)
+	}
+}